@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// acmeCmd represents the acme command
+var acmeCmd = &cobra.Command{
+	Use:   "acme",
+	Short: "Manage ACME DNS-01 challenge records",
+	Long: `Create and remove the TXT records used by ACME DNS-01 challenges
+
+These commands are designed to be used directly as the DNS hook of an ACME
+client such as dehydrated, certbot, or lego. Each challenge for a domain is
+published as a TXT record named _acme-challenge.DOMAIN, with any leading *.
+removed from wildcard domains. If --zone is given, the domain must be within
+that zone; otherwise, the zone containing the domain is found automatically.
+
+Challenge values are added to the record set rather than replacing it, so
+several certificates can be validated at the same time, and cleaning up only
+removes the values that were added. The record set is deleted once it is empty.
+Changes are made with the same conflict detection as add and remove. A new
+record set is given the TTL from --ttl, which must be within the limits set for
+the zone with min-ttl and max-ttl; an existing record set keeps its TTL.
+
+Many ACME clients ask the certificate authority to check a challenge as soon as
+the hook returns. With --wait, or AZURE_WAIT=true for hooks that do not parse
+flags, deploying a challenge does not return until all of the zone's name
+servers serve it; see "az-dns wait --help" for details.
+
+Integrating with ACME clients:
+    dehydrated
+        Use a hook script that runs:
+            exec az-dns acme dehydrated "$@"
+        Hooks other than deploy_challenge and clean_challenge are ignored, and
+        HOOK_CHAIN=yes is supported.
+    certbot
+        Pass --manual-auth-hook "az-dns acme deploy-challenge" and
+        --manual-cleanup-hook "az-dns acme clean-challenge". The domain and
+        value are read from $CERTBOT_DOMAIN and $CERTBOT_VALIDATION.
+    lego
+        Use the exec provider with EXEC_PATH set to a script that runs:
+            exec az-dns acme lego "$@"
+        Both the default and RAW values of EXEC_MODE are supported.
+
+Credentials, and the zone and resource group if they are given, are usually
+best provided through environment variables or a config file, since most ACME
+clients do not allow extra arguments to be passed to their hooks.`,
+}
+
+func init() {
+	rootCmd.AddCommand(acmeCmd)
+
+	acmeCmd.PersistentFlags().Int64P("ttl", "t", 60, "TTL of newly-created challenge record sets")
+	acmeCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times to retry after a conflict")
+	addWaitFlags(acmeCmd.PersistentFlags())
+	if err := viper.BindPFlags(acmeCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// acmeChallenge is a single DNS-01 challenge: the fully-qualified name of the
+// TXT record and the value it must contain.
+type acmeChallenge struct {
+	fqdn  string
+	value string
+}
+
+// getAcmeChallenges returns the challenges described by args, which may be a
+// DOMAIN and VALUE, any number of dehydrated-style DOMAIN TOKEN_FILENAME VALUE
+// triples, or nothing at all, in which case the challenge is taken from the
+// environment variables set by certbot.
+func getAcmeChallenges(args []string) ([]acmeChallenge, error) {
+	if len(args) == 0 {
+		domain := os.Getenv("CERTBOT_DOMAIN")
+		value := os.Getenv("CERTBOT_VALIDATION")
+		if domain == "" || value == "" {
+			return nil, &validationError{fmt.Errorf("a domain and value are required, either as arguments or in $CERTBOT_DOMAIN and $CERTBOT_VALIDATION")}
+		}
+		return []acmeChallenge{{helpers.AcmeChallengeFqdn(domain), value}}, nil
+	}
+
+	if len(args) == 2 {
+		return []acmeChallenge{{helpers.AcmeChallengeFqdn(args[0]), args[1]}}, nil
+	}
+
+	if len(args)%3 != 0 {
+		return nil, &validationError{fmt.Errorf("expected DOMAIN VALUE or one or more DOMAIN TOKEN_FILENAME VALUE triples, got %v arguments", len(args))}
+	}
+
+	challenges := []acmeChallenge{}
+	for i := 0; i < len(args); i += 3 {
+		challenges = append(challenges, acmeChallenge{helpers.AcmeChallengeFqdn(args[i]), args[i+2]})
+	}
+
+	return challenges, nil
+}
+
+// runAcmeChallenges adds the value of each challenge to its TXT record set if
+// deploy is true, and removes it otherwise. Without --zone, each challenge is
+// made in the zone that contains it, using the defaults in the config file for
+// that zone, which are not applied globally, so that they do not affect the
+// challenges in other zones.
+func runAcmeChallenges(cmd *cobra.Command, challenges []acmeChallenge, deploy bool) error {
+	client, err := newRecordSetClient()
+	if err != nil {
+		return err
+	}
+
+	format, err := getOutputFormat()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newCommandContext()
+	defer cancel()
+
+	locator := &zoneLocator{}
+	locations := []helpers.ZoneLocation{}
+	for _, challenge := range challenges {
+		resourceGroup, zone, _, err := locator.locate(ctx, challenge.fqdn, false)
+		if err != nil {
+			return err
+		}
+
+		if !helpers.InZone(challenge.fqdn, zone) {
+			return &validationError{fmt.Errorf("%v is not in the zone %v", challenge.fqdn, zone)}
+		}
+
+		locations = append(locations, helpers.ZoneLocation{Name: zone, ResourceGroup: resourceGroup})
+	}
+
+	cmd.SilenceUsage = true
+
+	flags := cmd.Flags()
+	retries := viper.GetInt("conflict-retries")
+	results := []resultOutput{}
+
+	// changes holds the records changed in each record set, keyed by the
+	// record set's location and name.
+	type changedRecordSet struct {
+		location   helpers.ZoneLocation
+		recordName string
+	}
+	changes := map[changedRecordSet]*dns.RecordSet{}
+	order := []changedRecordSet{}
+
+	for i, challenge := range challenges {
+		location := locations[i]
+		recordName := helpers.GenerateRecordName(challenge.fqdn, location.Name, false)
+		ttl := cast.ToInt64(zoneSetting(flags, location.Name, "ttl"))
+		params, err := generateTxtRecordParams(ttl, []string{challenge.value})
+		if err != nil {
+			return err
+		}
+
+		modify, result := removeRecordsModifier(dns.TXT, params), "removed"
+		if deploy {
+			modify, result = addRecordsModifier(dns.TXT, params, nil), "added"
+		}
+		modify = limitTTL(zoneTTLLimits(flags, location.Name), modify)
+
+		if _, err := helpers.ModifyRecordSet(ctx, client, location.ResourceGroup, location.Name, recordName, dns.TXT, retries, modify); err != nil {
+			return err
+		}
+
+		key := changedRecordSet{location, recordName}
+		if previous, ok := changes[key]; ok {
+			params.RecordSetProperties, _ = helpers.AddRecords(dns.TXT, previous.RecordSetProperties, params.RecordSetProperties)
+		} else {
+			order = append(order, key)
+		}
+		changes[key] = params
+
+		results = append(results, resultOutput{
+			Name:   recordName,
+			Zone:   location.Name,
+			Type:   string(dns.TXT),
+			Result: result,
+		})
+	}
+
+	for _, key := range order {
+		if !cast.ToBool(zoneSetting(flags, key.location.Name, "wait")) {
+			continue
+		}
+
+		props := changes[key].RecordSetProperties
+		if err := waitForPropagation(ctx, flags, key.location.ResourceGroup, key.location.Name, key.recordName, dns.TXT, props, deploy); err != nil {
+			return err
+		}
+	}
+
+	return printResults(os.Stdout, format, results)
+}
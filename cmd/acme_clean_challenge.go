@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// acmeCleanChallengeCmd represents the acme clean-challenge command
+var acmeCleanChallengeCmd = &cobra.Command{
+	Use:     "clean-challenge [DOMAIN [TOKEN_FILENAME] VALUE]...",
+	Aliases: []string{"clean_challenge"},
+	Short:   "Remove ACME DNS-01 challenge values",
+	Long: `Remove ACME DNS-01 challenge values from their TXT records
+
+This will remove VALUE from the TXT record set for _acme-challenge.DOMAIN,
+leaving any other values it contains alone, and delete the record set if no
+values remain. Arguments are accepted in the same forms as deploy-challenge,
+including the certbot environment variables, which certbot also sets for its
+--manual-cleanup-hook.
+
+Challenge values may begin with a dash, so when they are passed as arguments,
+it is safest to precede them with --.
+
+Examples:
+    az-dns acme clean-challenge -z example.com -- example.com abc123
+        Removes "abc123" from the TXT records for _acme-challenge.example.com`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		challenges, err := getAcmeChallenges(args)
+		if err != nil {
+			return err
+		}
+
+		return runAcmeChallenges(cmd, challenges, false)
+	},
+}
+
+func init() {
+	acmeCmd.AddCommand(acmeCleanChallengeCmd)
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// acmeDehydratedCmd represents the acme dehydrated command
+var acmeDehydratedCmd = &cobra.Command{
+	Use:   "dehydrated HOOK [ARGS]...",
+	Short: "Act as a dehydrated hook script",
+	Long: `Handle a call to a dehydrated hook script
+
+This accepts the arguments that dehydrated passes to its hook script. The
+deploy_challenge and clean_challenge hooks behave like deploy-challenge and
+clean-challenge, and every other hook is ignored, so a complete hook script can
+be as simple as:
+    #!/bin/sh
+    exec az-dns acme dehydrated "$@"
+
+Because challenge tokens may begin with a dash, flags are not parsed, and the
+zone, resource group, and credentials must be provided through environment
+variables or a config file.
+
+Examples:
+    AZURE_ZONE=example.com az-dns acme dehydrated deploy_challenge example.com - abc123
+        Adds "abc123" to the TXT records for _acme-challenge.example.com`,
+	Args:               cobra.MinimumNArgs(1),
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var deploy bool
+		switch args[0] {
+		case "deploy_challenge":
+			deploy = true
+		case "clean_challenge":
+			deploy = false
+		default:
+			return nil
+		}
+
+		challenges, err := getAcmeChallenges(args[1:])
+		if err != nil {
+			return err
+		}
+
+		return runAcmeChallenges(cmd, challenges, deploy)
+	},
+}
+
+func init() {
+	acmeCmd.AddCommand(acmeDehydratedCmd)
+}
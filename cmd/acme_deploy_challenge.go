@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// acmeDeployChallengeCmd represents the acme deploy-challenge command
+var acmeDeployChallengeCmd = &cobra.Command{
+	Use:     "deploy-challenge [DOMAIN [TOKEN_FILENAME] VALUE]...",
+	Aliases: []string{"deploy_challenge"},
+	Short:   "Publish ACME DNS-01 challenge values",
+	Long: `Add ACME DNS-01 challenge values to their TXT records
+
+This will add VALUE to the TXT record set for _acme-challenge.DOMAIN, creating
+it if necessary, without disturbing any other values it contains. Arguments
+may be given as DOMAIN VALUE, or as one or more DOMAIN TOKEN_FILENAME VALUE
+triples as passed by dehydrated, in which case TOKEN_FILENAME is ignored. If no
+arguments are given, the domain and value are read from $CERTBOT_DOMAIN and
+$CERTBOT_VALIDATION, as set by certbot's --manual-auth-hook.
+
+Challenge values may begin with a dash, so when they are passed as arguments,
+it is safest to precede them with --.
+
+Examples:
+    az-dns acme deploy-challenge -z example.com -- example.com abc123
+        Adds "abc123" to the TXT records for _acme-challenge.example.com
+    az-dns acme deploy-challenge -z example.com -- '*.example.com' abc123
+        Does the same for a wildcard certificate`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		challenges, err := getAcmeChallenges(args)
+		if err != nil {
+			return err
+		}
+
+		return runAcmeChallenges(cmd, challenges, true)
+	},
+}
+
+func init() {
+	acmeCmd.AddCommand(acmeDeployChallengeCmd)
+}
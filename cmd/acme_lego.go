@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+)
+
+// acmeLegoCmd represents the acme lego command
+var acmeLegoCmd = &cobra.Command{
+	Use:   "lego present|cleanup ARGS...",
+	Short: "Act as a program for lego's exec DNS provider",
+	Long: `Handle a call from lego's exec DNS provider
+
+This accepts the arguments that lego's exec provider passes to its program:
+    present|cleanup FQDN VALUE
+        in the default mode, where FQDN is the full name of the challenge
+        record and VALUE is the value to add or remove
+    present|cleanup -- DOMAIN TOKEN KEY_AUTH
+        when EXEC_MODE=RAW, in which case the challenge record name and value
+        are derived from DOMAIN and KEY_AUTH
+The present action behaves like deploy-challenge, and cleanup like
+clean-challenge.
+
+Because challenge values may begin with a dash, flags are not parsed, and the
+zone, resource group, and credentials must be provided through environment
+variables or a config file.
+
+Examples:
+    AZURE_ZONE=example.com az-dns acme lego present _acme-challenge.example.com. abc123
+        Adds "abc123" to the TXT records for _acme-challenge.example.com`,
+	Args:               cobra.MinimumNArgs(1),
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var deploy bool
+		switch args[0] {
+		case "present":
+			deploy = true
+		case "cleanup":
+			deploy = false
+		default:
+			return &validationError{fmt.Errorf("unknown action %q, must be present or cleanup", args[0])}
+		}
+
+		var challenge acmeChallenge
+		switch {
+		case len(args) == 3:
+			challenge = acmeChallenge{strings.TrimRight(args[1], "."), args[2]}
+		case len(args) == 5 && args[1] == "--":
+			challenge = acmeChallenge{helpers.AcmeChallengeFqdn(args[2]), helpers.AcmeChallengeValue(args[4])}
+		default:
+			return &validationError{fmt.Errorf("expected FQDN VALUE or -- DOMAIN TOKEN KEY_AUTH after %v", args[0])}
+		}
+
+		return runAcmeChallenges(cmd, []acmeChallenge{challenge}, deploy)
+	},
+}
+
+func init() {
+	acmeCmd.AddCommand(acmeLegoCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// addCmd represents the add command
+var addCmd = &cobra.Command{
+	Use:   "add TYPE HOSTNAME VALUES",
+	Short: "Add records to a DNS record set",
+	Long: `Add records to a record set in Azure DNS
+
+This will add records to a record set, creating it if it does not exist, while
+leaving any records already in it alone. Records that are already present are
+not duplicated. VALUES and HOSTNAME are given exactly as they are for set; see
+"az-dns set --help" for details.
+
+Unlike set, add is safe to use when several clients share a record set, such as
+the _acme-challenge TXT record set when multiple certificates are validated at
+once. The record set is read and then written back only if it has not been
+changed in the meantime; if it has, the whole operation is retried with backoff.
+If the retries run out, az-dns exits with status 5.
+
+A new record set is given the TTL from --ttl. An existing record set keeps its
+TTL unless --ttl is given explicitly. A new TTL must be within the limits set
+for the zone with min-ttl and max-ttl.
+
+With --wait, add does not return until the zone's name servers serve the added
+records; see "az-dns wait --help" for details.
+
+Examples:
+    az-dns add TXT _acme-challenge token -z example.com
+        Adds "token" to the TXT records for _acme-challenge.example.com
+    az-dns add A www 1.1.1.1 2.2.2.2 -z example.com
+        Adds 1.1.1.1 and 2.2.2.2 to the A records for www.example.com`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recordType := dns.RecordType(strings.ToUpper(args[0]))
+		hostname := args[1]
+		records := args[2:]
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		ttl := viper.GetInt64("ttl")
+		recordName := helpers.GenerateRecordName(hostname, zone, relative)
+		if err := checkRecordName(recordName, recordType); err != nil {
+			return &validationError{err}
+		}
+
+		additions, err := generateArgsRecordParams(recordType, ttl, records)
+		if err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		if err := checkCNAMEConflicts(ctx, client, resourceGroup, zone, recordName, recordType); err != nil {
+			return err
+		}
+
+		var newTTL *int64
+		if cmd.Flags().Changed("ttl") {
+			newTTL = &ttl
+		}
+
+		modify := limitTTL(helpers.GetTTLLimits(), addRecordsModifier(recordType, additions, newTTL))
+		if isDryRun() {
+			return previewChange(ctx, os.Stdout, client, format, resourceGroup, zone, recordName, recordType, modify)
+		}
+
+		rrset, err := helpers.ModifyRecordSet(ctx, client, resourceGroup, zone, recordName, recordType,
+			viper.GetInt("conflict-retries"), modify)
+		if err != nil {
+			return err
+		}
+
+		if viper.GetBool("wait") {
+			if err := waitForPropagation(ctx, cmd.Flags(), resourceGroup, zone, recordName, recordType, additions.RecordSetProperties, true); err != nil {
+				return err
+			}
+		}
+
+		if format == outputText {
+			fmt.Println("success")
+			return nil
+		}
+
+		return printRecordSet(os.Stdout, format, *rrset)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+
+	addCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	addCmd.PersistentFlags().Bool("quoted", false, "Each TXT value is one or more quoted strings, as in a zone file")
+	addCmd.PersistentFlags().Int64P("ttl", "t", 300, "Record set TTL")
+	addCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times to retry after a conflict")
+	addWaitFlags(addCmd.PersistentFlags())
+	if err := viper.BindPFlags(addCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// addRecordsModifier returns a modifier that adds the records in additions to
+// a record set, creating it from additions if it does not exist. If ttl is not
+// nil, the TTL of an existing record set is changed to it.
+func addRecordsModifier(recordType dns.RecordType, additions *dns.RecordSet, ttl *int64) helpers.RecordSetModifier {
+	return func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		if current == nil {
+			return additions, nil
+		}
+
+		props, err := helpers.AddRecords(recordType, current.RecordSetProperties, additions.RecordSetProperties)
+		if err != nil {
+			return nil, err
+		}
+		if ttl != nil {
+			props.TTL = ttl
+		}
+
+		desired := &dns.RecordSet{Type: current.Type, RecordSetProperties: props}
+		if helpers.RecordSetsEqual(*current, *desired) {
+			return current, nil
+		}
+
+		return desired, nil
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply FILE",
+	Short: "Bring a zone to its desired state",
+	Long: `Apply the changes needed to make a DNS zone match a desired-state file
+
+This will compare the zone with a YAML or JSON desired-state document, exactly
+as plan does, and then make the changes it shows. See "az-dns plan --help" for
+the format of the document. The plan is printed before any changes are made.
+
+Record sets in the zone that are not in the document are left alone unless
+--prune is given, in which case they are deleted. The SOA record set and the NS
+record set at the apex are never deleted. If a record set in the document has
+no metadata, any metadata it already has in the zone is kept.
+
+Each change is made conditionally on the record set being unchanged since the
+zone was read, so a concurrent modification causes apply to stop with an error
+rather than overwrite it. Changes already made at that point are not undone;
+running apply again will complete the remainder.
+
+Examples:
+    az-dns apply example.com.yaml
+        Creates and updates record sets in example.com to match
+        example.com.yaml
+    az-dns apply example.com.yaml --prune
+        Also deletes record sets that are not in example.com.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		state, err := readDesiredState(args[0])
+		if err != nil {
+			return &validationError{err}
+		}
+
+		resourceGroup, zone, err := getDesiredZoneInfo(state)
+		if err != nil {
+			return err
+		}
+
+		desired, err := desiredRecordSets(args[0], zone, state)
+		if err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		current, err := helpers.ListRecordSets(ctx, client, resourceGroup, zone, "", nil, "")
+		if err != nil {
+			return err
+		}
+
+		changes := helpers.DiffRecordSets(current, desired, viper.GetBool("prune"))
+		if err := checkChangeTTLs(helpers.GetTTLLimits(), changes); err != nil {
+			return err
+		}
+		if err := printPlan(os.Stdout, format, resourceGroup, zone, changes); err != nil {
+			return err
+		}
+
+		for i, change := range changes {
+			if err := applyChange(ctx, client, resourceGroup, zone, change); err != nil {
+				return fmt.Errorf("failed to %v %v record set %v after making %v of %v changes: %v",
+					change.Action, change.Type, change.Name, i, len(changes), err)
+			}
+		}
+
+		if format == outputText && len(changes) > 0 {
+			fmt.Println("success")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.PersistentFlags().Bool("prune", false, "Delete record sets that are not in the desired state")
+	if err := viper.BindPFlags(applyCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// applyChange makes a single planned change to a zone. Updates and deletions
+// require the record set's etag to be unchanged, and creations require that the
+// record set still not exist.
+func applyChange(ctx context.Context, client *dns.RecordSetsClient, resourceGroup string, zone string, change helpers.RecordSetChange) error {
+	switch change.Action {
+	case helpers.ChangeCreate:
+		_, err := client.CreateOrUpdate(ctx, resourceGroup, zone, change.Name, change.Type, *change.Desired, "", "*")
+		return err
+	case helpers.ChangeUpdate:
+		rrset := *change.Desired
+		props := *rrset.RecordSetProperties
+		if props.Metadata == nil {
+			props.Metadata = change.Current.Metadata
+		}
+		rrset.RecordSetProperties = &props
+
+		_, err := client.CreateOrUpdate(ctx, resourceGroup, zone, change.Name, change.Type, rrset, to.String(change.Current.Etag), "")
+		return err
+	case helpers.ChangeDelete:
+		_, err := client.Delete(ctx, resourceGroup, zone, change.Name, change.Type, to.String(change.Current.Etag))
+		return err
+	}
+
+	return fmt.Errorf("unknown change action %q", change.Action)
+}
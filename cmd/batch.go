@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// batchCmd represents the batch command
+var batchCmd = &cobra.Command{
+	Use:   "batch [FILE]",
+	Short: "Run many record set operations at once",
+	Long: `Run a batch of record set operations read from a file or stdin
+
+This reads operations from FILE, or from stdin if FILE is - or not given, and
+performs them all with a single set of credentials. Each line of the batch is
+one operation, written like the arguments of the matching command:
+    set TYPE HOSTNAME VALUES...
+    add TYPE HOSTNAME VALUES...
+    remove TYPE HOSTNAME VALUES...
+    clear TYPE HOSTNAME
+Fields are separated by whitespace and may be quoted with single or double
+quotes, as in a shell. Blank lines and lines starting with # are ignored.
+
+A line may instead be a JSON object, which may also give the TTL of the record
+set:
+    {"op": "set", "type": "A", "name": "www", "values": ["1.1.1.1"], "ttl": 60}
+As with set and add, an existing record set keeps its TTL unless one is given
+on its line or with --ttl, and new TTLs must be within the zone's limits.
+
+Every line is checked before any change is made, and nothing is done if any of
+them is invalid. If no zone is given with --zone, the zone of each HOSTNAME is
+discovered as for other commands, and the zone's defaults in the config file,
+such as its TTL limits, apply to that line alone. Operations on the same record set are
+performed in the order they appear; up to --concurrency record sets are changed
+at once. set, add, and remove read each record set before writing it, keeping
+its metadata, and retry after conflicting changes as "az-dns add" does.
+
+The result of each operation is reported, and az-dns exits with a non-zero
+status if any of them failed.
+
+Examples:
+    az-dns batch changes.txt -z example.com
+        Performs the operations in changes.txt in example.com
+    printf 'set A www 1.1.1.1\nclear TXT old\n' | az-dns batch -z example.com
+        Points www.example.com at 1.1.1.1 and deletes the TXT records for
+        old.example.com`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "-"
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		concurrency := viper.GetInt("concurrency")
+		if concurrency < 1 {
+			return &validationError{fmt.Errorf("concurrency must be at least 1")}
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		// Problems with the batch itself are reported by line, so usage
+		// information would not help.
+		cmd.SilenceUsage = true
+
+		operations, err := readBatch(path)
+		if err != nil {
+			return err
+		}
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		jobs, err := planBatch(ctx, client, operations, cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		results := runBatch(ctx, client, jobs, concurrency, viper.GetInt("conflict-retries"))
+		if err := printBatchResults(os.Stdout, format, results); err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%v of %v operations failed", failed, len(results))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.PersistentFlags().BoolP("relative", "r", false, "Each HOSTNAME is a zone-relative label")
+	batchCmd.PersistentFlags().Int64P("ttl", "t", 300, "Record set TTL")
+	batchCmd.PersistentFlags().Int("concurrency", 4, "Maximum number of record sets to change at once")
+	batchCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times to retry after a conflict")
+	if err := viper.BindPFlags(batchCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// readBatch reads the operations in the batch file at path, or on stdin if
+// path is -.
+func readBatch(path string) ([]helpers.BatchOperation, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	operations, err := helpers.ParseBatch(r)
+	if err != nil {
+		if _, ok := err.(*helpers.BatchError); ok {
+			return nil, &validationError{fmt.Errorf("invalid batch; no changes were made:\n%v", err)}
+		}
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// batchJob is a batch operation ready to be performed.
+type batchJob struct {
+	operation     helpers.BatchOperation
+	resourceGroup string
+	zone          string
+	recordName    string
+	params        *dns.RecordSet
+	// ttl is the TTL to give an existing record set, or nil to leave it
+	// unchanged.
+	ttl *int64
+	// limits are the TTL limits for the zone.
+	limits helpers.TTLLimits
+}
+
+// planBatch locates the record set affected by each operation and converts
+// its values to records. If any operation cannot be performed, an error
+// listing all of them is returned, unless planning fails for another reason,
+// such as a network error, which is returned as is. flags are those of the
+// batch command. Each
+// operation uses the defaults in the config file for its own zone, which are
+// not applied globally, so that they do not affect other operations.
+func planBatch(ctx context.Context, client *dns.RecordSetsClient, operations []helpers.BatchOperation,
+	flags *pflag.FlagSet) ([]batchJob, error) {
+	relative := viper.GetBool("relative")
+	locator := &zoneLocator{}
+
+	jobs := []batchJob{}
+	errs := []string{}
+	for _, operation := range operations {
+		job, err := planBatchOperation(ctx, client, locator, operation, relative, flags)
+		if err != nil {
+			// Other failures, such as network errors, are not problems with
+			// the operation, and end planning at once.
+			if _, notFound := err.(*helpers.ZoneNotFoundError); !notFound && !isValidationError(err) {
+				return nil, err
+			}
+			errs = append(errs, fmt.Sprintf("line %v: %v", operation.Line, err))
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	if len(errs) > 0 {
+		return nil, &validationError{fmt.Errorf("invalid batch; no changes were made:\n%v", strings.Join(errs, "\n"))}
+	}
+
+	return jobs, nil
+}
+
+// planBatchOperation prepares a single operation for planBatch.
+func planBatchOperation(ctx context.Context, client *dns.RecordSetsClient, locator *zoneLocator,
+	operation helpers.BatchOperation, relative bool, flags *pflag.FlagSet) (batchJob, error) {
+	job := batchJob{operation: operation}
+
+	resourceGroup, zone, _, err := locator.locate(ctx, operation.Name, relative)
+	if err != nil {
+		return job, err
+	}
+	job.resourceGroup, job.zone = resourceGroup, zone
+	job.recordName = helpers.GenerateRecordName(operation.Name, zone, relative)
+	if operation.Op == helpers.BatchSet || operation.Op == helpers.BatchAdd {
+		if err := checkRecordName(job.recordName, operation.Type); err != nil {
+			return job, &validationError{err}
+		}
+		if err := checkCNAMEConflicts(ctx, client, resourceGroup, zone, job.recordName, operation.Type); err != nil {
+			return job, err
+		}
+	}
+	job.limits = zoneTTLLimits(flags, zone)
+
+	ttl := cast.ToInt64(zoneSetting(flags, zone, "ttl"))
+	if operation.TTL != nil {
+		ttl = *operation.TTL
+	}
+	if operation.TTL != nil || flags.Changed("ttl") {
+		job.ttl = &ttl
+		if operation.Op != helpers.BatchRemove && operation.Op != helpers.BatchClear {
+			if err := job.limits.Check(ttl); err != nil {
+				return job, err
+			}
+		}
+	}
+
+	if operation.Op == helpers.BatchClear {
+		return job, nil
+	}
+
+	job.params, err = generateRecordParams(operation.Type, ttl, operation.Values)
+	if err != nil {
+		return job, &validationError{err}
+	}
+
+	return job, nil
+}
+
+// runBatch performs jobs, changing up to concurrency record sets at once, and
+// returns the result of each in order. Jobs affecting the same record set are
+// performed one at a time, in order. retries is passed to ModifyRecordSet.
+func runBatch(ctx context.Context, client *dns.RecordSetsClient, jobs []batchJob, concurrency int,
+	retries int) []batchResultOutput {
+	type recordSetKey struct {
+		resourceGroup string
+		zone          string
+		recordName    string
+		recordType    dns.RecordType
+	}
+
+	groups := map[recordSetKey][]int{}
+	order := []recordSetKey{}
+	for i, job := range jobs {
+		key := recordSetKey{
+			resourceGroup: strings.ToLower(job.resourceGroup),
+			zone:          strings.ToLower(job.zone),
+			recordName:    strings.ToLower(job.recordName),
+			recordType:    job.operation.Type,
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	results := make([]batchResultOutput, len(jobs))
+	queue := make(chan []int)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range queue {
+				for _, i := range group {
+					results[i] = runBatchJob(ctx, client, jobs[i], retries)
+				}
+			}
+		}()
+	}
+
+	for _, key := range order {
+		queue <- groups[key]
+	}
+	close(queue)
+	wg.Wait()
+
+	return results
+}
+
+// runBatchJob performs a single job and reports its result.
+func runBatchJob(ctx context.Context, client *dns.RecordSetsClient, job batchJob, retries int) batchResultOutput {
+	operation := job.operation
+	result := batchResultOutput{
+		Line:      operation.Line,
+		Operation: operation.Op,
+		Name:      job.recordName,
+		Zone:      job.zone,
+		Type:      string(operation.Type),
+	}
+
+	var err error
+	switch operation.Op {
+	case helpers.BatchSet:
+		_, err = helpers.ModifyRecordSet(ctx, client, job.resourceGroup, job.zone, job.recordName, operation.Type, retries,
+			limitTTL(job.limits, setRecordsModifier(job.params, nil, job.ttl)))
+		result.Result = "updated"
+	case helpers.BatchAdd:
+		_, err = helpers.ModifyRecordSet(ctx, client, job.resourceGroup, job.zone, job.recordName, operation.Type, retries,
+			limitTTL(job.limits, addRecordsModifier(operation.Type, job.params, job.ttl)))
+		result.Result = "added"
+	case helpers.BatchRemove:
+		_, err = helpers.ModifyRecordSet(ctx, client, job.resourceGroup, job.zone, job.recordName, operation.Type, retries,
+			removeRecordsModifier(operation.Type, job.params))
+		result.Result = "removed"
+	case helpers.BatchClear:
+		_, err = client.Delete(ctx, job.resourceGroup, job.zone, job.recordName, operation.Type, "")
+		result.Result = "deleted"
+	}
+
+	if err != nil {
+		result.Result = "failed"
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
@@ -23,6 +23,19 @@ either the empty string or @ for the apex. If a record name contains the zone
 name (e.g. example.com.example.com), you should either provide the FQDN or use
 the --relative flag.
 
+To avoid deleting a record set that someone else has changed since you read
+it, pass --if-match with the etag reported by "az-dns get -o json".
+Alternatively, --optimistic reads the record set's current etag and deletes
+conditionally on it, retrying with backoff if another client changes the record
+set in between. If the etag no longer matches, or --optimistic runs out of
+retries, nothing is deleted and az-dns exits with status 5.
+
+With --dry-run, clear prints the records it would delete instead of deleting
+them.
+
+With --wait, clear does not return until the zone's name servers no longer
+serve the record set; see "az-dns wait --help" for details.
+
 Examples:
     az-dns clear A example.com -z example.com
         Removes the A record at the apex of example.com
@@ -35,37 +48,66 @@ Examples:
 		recordType := dns.RecordType(strings.ToUpper(args[0]))
 		hostname := args[1]
 
-		client, err := helpers.NewRecordSetClient(dns.DefaultBaseURI)
+		client, err := newRecordSetClient()
 		if err != nil {
 			return err
 		}
 
-		resourceGroup := viper.GetString("resource-group")
-		if resourceGroup == "" {
-			return fmt.Errorf("a resource group name is required")
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
 		}
 
-		zone := viper.GetString("zone")
-		if zone == "" {
-			return fmt.Errorf("a DNS zone name is required")
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		ifMatch, _, err := getConditions()
+		if err != nil {
+			return err
+		}
+
+		optimistic := viper.GetBool("optimistic")
+		if optimistic && ifMatch != "" {
+			return &validationError{fmt.Errorf("--optimistic cannot be combined with --if-match")}
 		}
 
 		cmd.SilenceUsage = true
 
-		relative := viper.GetBool("relative")
 		recordName := helpers.GenerateRecordName(hostname, zone, relative)
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		remove := func(*dns.RecordSet) (*dns.RecordSet, error) { return nil, nil }
+		if isDryRun() {
+			return previewChange(ctx, os.Stdout, client, format, resourceGroup, zone, recordName, recordType, remove)
+		}
 
-		_, err = client.Delete(ctx, resourceGroup, zone, recordName, recordType, "")
+		if optimistic {
+			_, err = helpers.ModifyRecordSet(ctx, client, resourceGroup, zone, recordName, recordType,
+				viper.GetInt("conflict-retries"), remove)
+		} else {
+			_, err = client.Delete(ctx, resourceGroup, zone, recordName, recordType, ifMatch)
+		}
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("success")
+		if viper.GetBool("wait") {
+			if err := waitForPropagation(ctx, cmd.Flags(), resourceGroup, zone, recordName, recordType, nil, false); err != nil {
+				return err
+			}
+		}
 
-		return nil
+		return printResult(os.Stdout, format, resultOutput{
+			Name:   recordName,
+			Zone:   zone,
+			Type:   string(recordType),
+			Result: "deleted",
+		})
 	},
 }
 
@@ -73,6 +115,10 @@ func init() {
 	rootCmd.AddCommand(clearCmd)
 
 	clearCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	clearCmd.PersistentFlags().String("if-match", "", "Only delete the record set if its etag is ETAG")
+	clearCmd.PersistentFlags().Bool("optimistic", false, "Read the record set's etag first and retry if it changes concurrently")
+	clearCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times --optimistic retries after a conflict")
+	addWaitFlags(clearCmd.PersistentFlags())
 	if err := viper.BindPFlags(clearCmd.PersistentFlags()); err != nil {
 		// This shouldn't happen
 		panic(err)
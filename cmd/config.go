@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elyscape/az-dns/helpers"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage configuration profiles",
+	Long: `Inspect and change the settings in the az-dns config file
+
+Any flag may also be set in the config file, using the flag's long name as the
+key. Settings may be grouped into named profiles, selected with --profile or
+$AZURE_PROFILE, or by default with the profile key. A profile's settings
+override those at the top level of the file, and are in turn overridden by
+environment variables and flags.
+
+Defaults for individual zones are kept under the zones key, either at the top
+level or within a profile, and apply whenever the zone is selected with --zone.
+They are most useful for the resource group, so that --zone alone is enough,
+and for the min-ttl and max-ttl limits on the TTLs of record sets:
+
+    tenant-id: abcdef12-3456-7890-abcd-ef1234567890
+    profile: staging
+    profiles:
+      prod:
+        subscription-id: 12345678-90ab-cdef-1234-567890abcdef
+        zones:
+          example.com:
+            resource-group: dns-prod
+            min-ttl: 300
+            max-ttl: 86400
+      staging:
+        subscription-id: fedcba09-8765-4321-fedc-ba0987654321
+        zones:
+          staging.example.com:
+            resource-group: dns-staging
+
+Profiles and zone defaults require a YAML or JSON config file.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Unlike other commands, these must work even if the selected
+		// profile does not exist, so that it can be created.
+		return viper.BindPFlags(cmd.Flags())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
+
+// configSettings holds the settings read from the config file, with those of
+// the selected profile applied. It is nil if the config file could not be
+// read as YAML or JSON.
+var configSettings map[string]interface{}
+
+// configError holds any error encountered while applying the selected profile,
+// which is reported when a command other than config is run.
+var configError error
+
+// loadProfile reads the config file and applies the settings of the profile
+// selected with --profile, $AZURE_PROFILE, or the profile key.
+func loadProfile() error {
+	settings, err := readConfigFile(viper.ConfigFileUsed())
+	if err != nil {
+		return err
+	}
+
+	profile := viper.GetString("profile")
+	if settings == nil {
+		if profile != "" {
+			return fmt.Errorf("profiles require a YAML or JSON config file")
+		}
+		return nil
+	}
+
+	if profile != "" {
+		if settings, err = helpers.ProfileSettings(settings, profile); err != nil {
+			return err
+		}
+	}
+
+	configSettings = settings
+	if profile == "" {
+		return nil
+	}
+	return useConfigSettings(settings)
+}
+
+// applyZoneDefaults applies the defaults in the config file for the zone, if
+// any. Like other config file settings, they are overridden by environment
+// variables and flags.
+func applyZoneDefaults(zone string) error {
+	if zone == "" || configSettings == nil {
+		return nil
+	}
+
+	defaults := helpers.ZoneSettings(configSettings, zone)
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	return useConfigSettings(helpers.MergeSettings(configSettings, defaults))
+}
+
+// zoneSetting returns the value of the setting key for zone without applying
+// the zone's defaults: a flag in flags or an environment variable takes
+// precedence, then the zone's defaults in the config file, and then the value
+// Viper would otherwise use.
+func zoneSetting(flags *pflag.FlagSet, zone string, key string) interface{} {
+	if flag := flags.Lookup(key); flag != nil && flag.Changed {
+		return viper.Get(key)
+	}
+	if _, ok := os.LookupEnv("AZURE_" + strings.ToUpper(strings.Replace(key, "-", "_", -1))); ok {
+		return viper.Get(key)
+	}
+
+	if configSettings != nil {
+		if value, ok := helpers.ZoneSettings(configSettings, zone)[key]; ok {
+			return value
+		}
+	}
+
+	return viper.Get(key)
+}
+
+// useConfigSettings replaces the settings Viper read from the config file.
+func useConfigSettings(settings map[string]interface{}) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	viper.SetConfigType("json")
+	return viper.ReadConfig(bytes.NewReader(data))
+}
+
+// configFileFormat returns the format of the config file at path, based on its
+// extension, or the empty string if it is neither YAML nor JSON.
+func configFileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return outputYAML
+	case ".json":
+		return outputJSON
+	default:
+		return ""
+	}
+}
+
+// readConfigFile returns the normalized settings in the config file at path.
+// A missing file has no settings, and nil is returned for a file that is
+// neither YAML nor JSON.
+func readConfigFile(path string) (map[string]interface{}, error) {
+	if path == "" {
+		return map[string]interface{}{}, nil
+	}
+
+	format := configFileFormat(path)
+	if format == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var settings interface{}
+	if format == outputYAML {
+		err = yaml.Unmarshal(data, &settings)
+	} else {
+		err = json.Unmarshal(data, &settings)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config file %v: %v", path, err)
+	}
+
+	if settings == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	normalized, ok := helpers.NormalizeSettings(settings).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config file %v does not contain a map of settings", path)
+	}
+
+	return normalized, nil
+}
+
+// writeConfigFile writes settings to the config file at path in the format
+// given by its extension. New files are only readable by their owner, since
+// they may contain secrets.
+func writeConfigFile(path string, settings map[string]interface{}) error {
+	var data []byte
+	var err error
+
+	switch configFileFormat(path) {
+	case outputYAML:
+		data, err = yaml.Marshal(settings)
+	case outputJSON:
+		data, err = json.MarshalIndent(settings, "", "  ")
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("config file %v must be YAML or JSON to be changed", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// getConfigFilePath returns the path to the config file in use, or the path
+// at which one should be created if there is none.
+func getConfigFilePath() (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path, nil
+	}
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".az-dns.yaml"), nil
+}
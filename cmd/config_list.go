@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configListCmd represents the config list command
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configuration profiles",
+	Long: `List the profiles in the config file
+
+The profile that is currently selected is marked with an asterisk.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		settings, err := readConfigFile(viper.ConfigFileUsed())
+		if err != nil {
+			return &validationError{err}
+		}
+
+		selected := viper.GetString("profile")
+		profiles := []profileOutput{}
+		for _, name := range helpers.ProfileNames(settings) {
+			profiles = append(profiles, profileOutput{Name: name, Selected: name == selected})
+		}
+
+		if format == outputJSON || format == outputYAML {
+			return printStructured(os.Stdout, format, profiles)
+		}
+
+		for _, profile := range profiles {
+			marker := " "
+			if profile.Selected {
+				marker = "*"
+			}
+			fmt.Println(marker, profile.Name)
+		}
+
+		return nil
+	},
+}
+
+// profileOutput is the representation of a profile emitted by the json and
+// yaml output formats.
+type profileOutput struct {
+	Name     string `json:"name" yaml:"name"`
+	Selected bool   `json:"selected" yaml:"selected"`
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Change a configuration setting",
+	Long: `Change a setting in the config file
+
+This will set KEY to VALUE in the selected profile, creating the profile and
+the config file if necessary. KEY is the long name of any flag. With --global,
+the setting is made at the top level of the config file instead, where it
+applies to every profile. With --for-zone, it is made as a default for the given
+zone. The profile key, which selects the default profile, is always set at the
+top level.
+
+The config file is rewritten in full, so any comments in it are lost.
+
+Examples:
+    az-dns config set subscription-id 12345678-90ab-cdef-1234-567890abcdef --profile prod
+        Sets the subscription ID used by the prod profile
+    az-dns config set resource-group dns-prod --for-zone example.com --profile prod
+        Makes dns-prod the resource group for example.com in the prod profile
+    az-dns config set profile prod
+        Makes prod the default profile`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := strings.ToLower(args[0])
+		value := args[1]
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		if !isConfigKey(key) {
+			return &validationError{fmt.Errorf("unknown setting %q; run \"az-dns config set --help\" for details", key)}
+		}
+
+		path, err := getConfigFilePath()
+		if err != nil {
+			return err
+		}
+
+		settings, err := readConfigFile(path)
+		if err != nil {
+			return &validationError{err}
+		}
+		if settings == nil {
+			return &validationError{fmt.Errorf("config file %v must be YAML or JSON to be changed", path)}
+		}
+
+		section := []string{}
+		if profile := viper.GetString("profile"); profile != "" && !viper.GetBool("global") && key != helpers.ProfileKey {
+			section = append(section, helpers.ProfilesKey, profile)
+		}
+		if zone := viper.GetString("for-zone"); zone != "" {
+			if key == helpers.ProfileKey {
+				return &validationError{fmt.Errorf("the profile setting cannot be made for a zone")}
+			}
+			section = append(section, helpers.ZonesKey, strings.TrimRight(zone, "."))
+		}
+
+		if err := helpers.SetSetting(settings, section, key, value); err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		if err := writeConfigFile(path, settings); err != nil {
+			return err
+		}
+
+		if format == outputJSON || format == outputYAML {
+			return printStructured(os.Stdout, format, helpers.RedactSettings(settings))
+		}
+
+		fmt.Println("success")
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configSetCmd)
+
+	configSetCmd.PersistentFlags().Bool("global", false, "Make the setting outside of any profile")
+	configSetCmd.PersistentFlags().String("for-zone", "", "Make the setting a default for this zone")
+	if err := viper.BindPFlags(configSetCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// isConfigKey reports whether key is the name of a flag of any command that
+// may be given in the config file.
+func isConfigKey(key string) bool {
+	switch key {
+	case "config", "help", helpers.ProfilesKey, helpers.ZonesKey:
+		return false
+	}
+
+	keys := configKeys(rootCmd)
+	i := sort.SearchStrings(keys, key)
+	return i < len(keys) && keys[i] == key
+}
+
+// configKeys returns the sorted names of the flags of cmd and its
+// subcommands.
+func configKeys(cmd *cobra.Command) []string {
+	seen := map[string]bool{}
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		addFlag := func(flag *pflag.Flag) { seen[flag.Name] = true }
+		cmd.LocalFlags().VisitAll(addFlag)
+		cmd.PersistentFlags().VisitAll(addFlag)
+		for _, child := range cmd.Commands() {
+			visit(child)
+		}
+	}
+	visit(cmd)
+
+	keys := []string{}
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show [PROFILE]",
+	Short: "Show configuration settings",
+	Long: `Show the settings in the config file that apply to a profile
+
+This will print the settings that are used with the given profile, or the
+selected one if none is given, combining those at the top level of the config
+file with those in the profile. Secrets, such as client secrets, are redacted.
+Settings from environment variables and flags are not included.
+
+The settings are printed as YAML unless --output json is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+		if format != outputJSON {
+			format = outputYAML
+		}
+
+		settings, err := readConfigFile(viper.ConfigFileUsed())
+		if err != nil {
+			return &validationError{err}
+		}
+		if settings == nil {
+			return &validationError{fmt.Errorf("config show requires a YAML or JSON config file")}
+		}
+
+		profile := viper.GetString("profile")
+		if len(args) > 0 {
+			profile = args[0]
+		}
+
+		if profile != "" {
+			if settings, err = helpers.ProfileSettings(settings, profile); err != nil {
+				return &validationError{err}
+			}
+		}
+		delete(settings, helpers.ProfilesKey)
+
+		return printStructured(os.Stdout, format, helpers.RedactSettings(settings))
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+}
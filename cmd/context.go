@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// shutdownGracePeriod is how long a command may keep running after it has
+// timed out or been interrupted before az-dns exits without waiting for it.
+// Some operations, such as acquiring tokens, cannot be canceled.
+var shutdownGracePeriod = 5 * time.Second
+
+// commandState records why the context of the running command ended.
+var commandState struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	timeout time.Duration
+	signal  os.Signal
+}
+
+// startCommandContext creates the context that bounds the command being
+// run. It is canceled when az-dns receives SIGINT or SIGTERM, and when the
+// time given by --timeout has passed, after which az-dns exits once the
+// command returns or shutdownGracePeriod has passed, whichever is sooner. A
+// second signal ends az-dns immediately.
+func startCommandContext() {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	timeout := viper.GetDuration("timeout")
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	commandState.mu.Lock()
+	commandState.ctx, commandState.timeout, commandState.signal = ctx, timeout, nil
+	commandState.mu.Unlock()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			commandState.mu.Lock()
+			commandState.signal = sig
+			commandState.mu.Unlock()
+			// Restore the default behavior, so that a second signal ends
+			// az-dns at once.
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signals)
+		}
+
+		time.Sleep(shutdownGracePeriod)
+		f := newFailure(commandError(), false)
+		reportFailure(os.Stderr, f)
+		os.Exit(f.ExitCode)
+	}()
+}
+
+// newCommandContext returns a context for the Azure requests made by a
+// command, which ends when the command times out or is interrupted.
+func newCommandContext() (context.Context, context.CancelFunc) {
+	commandState.mu.Lock()
+	parent := commandState.ctx
+	commandState.mu.Unlock()
+
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	return context.WithCancel(parent)
+}
+
+// commandError returns an error describing why the context of the running
+// command ended early, or nil if it did not.
+func commandError() error {
+	commandState.mu.Lock()
+	defer commandState.mu.Unlock()
+
+	if commandState.signal != nil {
+		return &interruptedError{signal: commandState.signal}
+	}
+	if commandState.ctx != nil && commandState.ctx.Err() == context.DeadlineExceeded {
+		return &timeoutError{timeout: commandState.timeout}
+	}
+
+	return nil
+}
+
+// timeoutError indicates that a command took longer than --timeout.
+type timeoutError struct {
+	timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %v", e.timeout)
+}
+
+// interruptedError indicates that a command was interrupted by a signal.
+type interruptedError struct {
+	signal os.Signal
+}
+
+func (e *interruptedError) Error() string {
+	return fmt.Sprintf("canceled by signal: %v", e.signal)
+}
+
+// exitStatus returns the conventional exit status of a process ended by the
+// signal: 128 plus the signal's number.
+func (e *interruptedError) exitStatus() int {
+	if sig, ok := e.signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+
+	return exitFailure
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/viper"
+)
+
+// changeNone is the action reported by --dry-run for a record set that would
+// not change.
+const changeNone = "none"
+
+// isDryRun reports whether --dry-run was given.
+func isDryRun() bool {
+	return viper.GetBool("dry-run")
+}
+
+// previewChange shows the change that modify would make to a record set,
+// without making it. The json and yaml formats print the action with the
+// current and desired record sets, as plan does; other formats print a unified
+// diff of the records in zone file presentation format.
+func previewChange(ctx context.Context, w io.Writer, client *dns.RecordSetsClient, format string, resourceGroup string, zone string,
+	name string, recordType dns.RecordType, modify helpers.RecordSetModifier) error {
+	current, desired, err := helpers.PreviewRecordSet(ctx, client, resourceGroup, zone, name, recordType, modify)
+	if err != nil {
+		return err
+	}
+
+	if desired != nil && desired != current {
+		// Record sets built from the command line have no name or type.
+		rrset := *desired
+		resourceType := "Microsoft.Network/dnszones/" + string(recordType)
+		rrset.Name, rrset.Type = &name, &resourceType
+		desired = &rrset
+	}
+
+	diff := helpers.RecordSetDiff(zone, name, recordType, current, desired)
+
+	if format == outputJSON || format == outputYAML {
+		output := changeOutput{
+			Action: string(helpers.ChangeUpdate),
+			Name:   name,
+			Type:   string(recordType),
+		}
+		switch {
+		case diff == "":
+			output.Action = changeNone
+		case current == nil:
+			output.Action = string(helpers.ChangeCreate)
+		case desired == nil:
+			output.Action = string(helpers.ChangeDelete)
+		}
+		if current != nil {
+			currentOut := newRecordSetOutput(*current)
+			output.Current = &currentOut
+		}
+		if desired != nil {
+			desiredOut := newRecordSetOutput(*desired)
+			output.Desired = &desiredOut
+		}
+
+		return printStructured(w, format, output)
+	}
+
+	if diff == "" {
+		_, err := fmt.Fprintf(w, "No changes to %v record set %v.\n", recordType, name)
+		return err
+	}
+
+	_, err = io.WriteString(w, diff)
+	return err
+}
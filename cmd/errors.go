@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+)
+
+// Kinds of failure, as reported in structured error output.
+const (
+	failureGeneral     = "failure"
+	failureUsage       = "usage"
+	failureNotFound    = "notFound"
+	failureAuth        = "auth"
+	failureConflict    = "conflict"
+	failureThrottled   = "throttled"
+	failureTimeout     = "timeout"
+	failureInterrupted = "interrupted"
+)
+
+// failure describes why a command failed, for people and for scripts. Its
+// Error method gives a concise message, without the layers of context added
+// by the Azure SDK.
+type failure struct {
+	Kind       string `json:"kind" yaml:"kind"`
+	Message    string `json:"message" yaml:"message"`
+	ExitCode   int    `json:"exitCode" yaml:"exitCode"`
+	StatusCode int    `json:"statusCode,omitempty" yaml:"statusCode,omitempty"`
+	Code       string `json:"code,omitempty" yaml:"code,omitempty"`
+	RequestID  string `json:"requestId,omitempty" yaml:"requestId,omitempty"`
+}
+
+func (f *failure) Error() string {
+	details := []string{}
+	if f.Code != "" {
+		details = append(details, f.Code)
+	}
+	if f.RequestID != "" {
+		details = append(details, "request ID "+f.RequestID)
+	}
+
+	if len(details) == 0 {
+		return f.Message
+	}
+
+	return fmt.Sprintf("%v (%v)", f.Message, strings.Join(details, ", "))
+}
+
+// validationError marks an error caused by invalid arguments, flags, settings,
+// or input. Only such errors are reported as usage errors when they are
+// returned by a command; other failures, such as a network error while finding
+// a zone, are not, however early they happen.
+type validationError struct {
+	error
+}
+
+// isValidationError reports whether err was caused by invalid input.
+func isValidationError(err error) bool {
+	switch err.(type) {
+	case *validationError, *helpers.TTLLimitError:
+		return true
+	}
+
+	return false
+}
+
+// newFailure classifies err and determines the exit status for it. usage
+// indicates that err came from parsing the command line rather than from a
+// command, in which case an error that did not come from Azure is a usage
+// error.
+func newFailure(err error, usage bool) *failure {
+	if f, ok := err.(*failure); ok {
+		return f
+	}
+
+	f := &failure{Kind: failureGeneral, Message: err.Error(), ExitCode: exitFailure}
+	if info, ok := helpers.DescribeAzureError(err); ok {
+		f.Message, f.StatusCode, f.Code, f.RequestID = info.Message, info.StatusCode, info.Code, info.RequestID
+	}
+
+	switch e := err.(type) {
+	case *timeoutError:
+		f.Kind, f.ExitCode = failureTimeout, exitTimeout
+		return f
+	case *interruptedError:
+		f.Kind, f.ExitCode = failureInterrupted, e.exitStatus()
+		return f
+	}
+
+	if isValidationError(err) {
+		f.Kind, f.ExitCode = failureUsage, exitUsage
+		return f
+	}
+
+	switch {
+	case helpers.IsConflict(err):
+		f.Kind, f.ExitCode = failureConflict, exitConflict
+	case helpers.IsAuthFailure(err):
+		f.Kind, f.ExitCode = failureAuth, exitAuth
+	case helpers.IsThrottled(err):
+		f.Kind, f.ExitCode = failureThrottled, exitThrottled
+	case helpers.IsNotFound(err):
+		f.Kind, f.ExitCode = failureNotFound, exitNotFound
+	case usage && f.StatusCode == 0:
+		f.Kind, f.ExitCode = failureUsage, exitUsage
+	}
+
+	return f
+}
+
+// reportFailure writes f to w as an error envelope in the format requested
+// with --output, if it is json or yaml, or otherwise as text.
+func reportFailure(w io.Writer, f *failure) {
+	if format, err := getOutputFormat(); err == nil && (format == outputJSON || format == outputYAML) {
+		envelope := struct {
+			Error *failure `json:"error" yaml:"error"`
+		}{f}
+		if err := printStructured(w, format, envelope); err == nil {
+			return
+		}
+	}
+
+	fmt.Fprintln(w, "Error:", f)
+}
+
+// reportCommandErrors wraps the commands in the tree rooted at cmd so that the
+// errors they return are described by a failure. A command that fails after
+// timing out or being interrupted reports that, rather than whichever request
+// happened to be canceled.
+func reportCommandErrors(cmd *cobra.Command) {
+	if run := cmd.RunE; run != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := run(cmd, args)
+			if err == nil {
+				return nil
+			}
+			if ctxErr := commandError(); ctxErr != nil {
+				err = ctxErr
+			}
+
+			f := newFailure(err, false)
+			// Usage information only helps with usage errors, and would get in
+			// the way of structured error output.
+			if f.Kind != failureUsage || cmd.Root().SilenceErrors {
+				cmd.SilenceUsage = true
+			}
+			return f
+		}
+	}
+
+	for _, child := range cmd.Commands() {
+		reportCommandErrors(child)
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type failureTestCase struct {
+	name     string
+	err      error
+	usage    bool
+	kind     string
+	exitCode int
+}
+
+var failureTests = []failureTestCase{
+	{"validation", &validationError{errors.New("bad flag")}, false, failureUsage, exitUsage},
+	{"TTL limit", &helpers.TTLLimitError{TTL: 60, Limits: helpers.TTLLimits{Min: 300}}, false, failureUsage, exitUsage},
+	{"command failure", errors.New("connection refused"), false, failureGeneral, exitFailure},
+	{"command line", errors.New("unknown flag: --bogus"), true, failureUsage, exitUsage},
+	{"zone not found", &helpers.ZoneNotFoundError{Name: "example.com"}, false, failureNotFound, exitNotFound},
+	{"timeout", &timeoutError{timeout: time.Second}, false, failureTimeout, exitTimeout},
+}
+
+func TestNewFailure(t *testing.T) {
+	for _, testCase := range failureTests {
+		t.Run(testCase.name, func(t *testing.T) { testNewFailure(t, testCase) })
+	}
+}
+
+func testNewFailure(t *testing.T, testCase failureTestCase) {
+	f := newFailure(testCase.err, testCase.usage)
+	assert.Equal(t, testCase.kind, f.Kind)
+	assert.Equal(t, testCase.exitCode, f.ExitCode)
+}
+
+func TestDiscoveryNetworkErrorIsNotUsageError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := dns.NewZonesClientWithBaseURI(server.URL, "subscription")
+	client.RetryAttempts = 0
+	_, discoverErr := helpers.DiscoverZone(context.Background(), &client, "", "www.example.com", helpers.ZoneCache{})
+	if !assert.Error(t, discoverErr) {
+		return
+	}
+
+	// The error is returned before the command silences its usage
+	// information, as it would be by getZoneInfo.
+	cmd := &cobra.Command{
+		Use:  "test",
+		RunE: func(cmd *cobra.Command, args []string) error { return discoverErr },
+	}
+	reportCommandErrors(cmd)
+
+	err := cmd.RunE(cmd, nil)
+	if f, ok := err.(*failure); assert.True(t, ok) {
+		assert.Equal(t, failureGeneral, f.Kind)
+		assert.Equal(t, exitFailure, f.ExitCode)
+	}
+	assert.True(t, cmd.SilenceUsage)
+}
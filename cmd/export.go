@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export [FILE]",
+	Short: "Export a DNS zone to a zone file",
+	Long: `Export the record sets of a zone as an RFC 1035 zone file
+
+This will write every record set in the zone to FILE, or to standard output if
+FILE is not provided, in the BIND master file format. Owner names are relative
+to the $ORIGIN, and domain names within records are fully-qualified. The $TTL
+is taken from the zone's SOA record, but every record is written with an
+explicit TTL.
+
+The output is deterministic: record sets are sorted by name and type, and the
+records within each set are sorted, so exporting an unchanged zone always
+produces an identical file. This makes exports suitable for backups and for
+tracking changes in version control.
+
+Examples:
+    az-dns export -z example.com
+        Prints the contents of example.com as a zone file
+    az-dns export example.com.zone -z example.com
+        Writes the contents of example.com to example.com.zone`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		resourceGroup, zone, err := getZoneInfo(ctx, "", false)
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		rrsets, err := helpers.ListRecordSets(ctx, client, resourceGroup, zone, "", nil, "")
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 0 {
+			return helpers.WriteZoneFile(os.Stdout, zone, rrsets)
+		}
+
+		file, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+
+		if err := helpers.WriteZoneFile(file, zone, rrsets); err != nil {
+			file.Close()
+			return err
+		}
+
+		return file.Close()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
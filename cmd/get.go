@@ -1,9 +1,8 @@
 package cmd
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
@@ -18,12 +17,23 @@ var getCmd = &cobra.Command{
 	Short: "Retrieve a DNS record set",
 	Long: `Retrieve a record set from Azure DNS
 
-This will print the contents of a particular record set on Azure DNS. The
-currently-supported record types are A, AAAA, CAA, CNAME, and TXT. HOSTNAME may
-be a fully-qualified domain name contained within the zone, a record name
-relative to the zone, or either the empty string or @ for the apex. If a record
-name contains the zone name (e.g. example.com.example.com), you should either
-provide the FQDN or use the --relative flag.
+This will print the contents of a particular record set on Azure DNS, one
+record per line. The currently-supported record types are A, AAAA, CAA, CNAME,
+MX, NS, PTR, SOA, SRV, and TXT. HOSTNAME may be a fully-qualified domain name
+contained within the zone, a record name relative to the zone, or either the
+empty string or @ for the apex. If a record name contains the zone name (e.g.
+example.com.example.com), you should either provide the FQDN or use the
+--relative flag.
+
+Records made up of multiple fields are printed in zone file order:
+    CAA    FLAGS TAG "VALUE"
+    MX     PREFERENCE EXCHANGE
+    SRV    PRIORITY WEIGHT PORT TARGET
+    SOA    HOST EMAIL SERIAL REFRESH RETRY EXPIRE MINIMUM-TTL
+
+A TXT record may be made up of several strings of up to 255 bytes each, which
+resolvers join together. They are printed joined, as a single value; with
+--raw, each is printed quoted, as in a zone file.
 
 Examples:
     az-dns get A example.com -z example.com
@@ -31,82 +41,55 @@ Examples:
     az-dns get AAAA sub -z example.com
         Prints AAAA records for sub.example.com
     az-dns get CNAME sub.example.com -r -z example.com
-        Prints the CNAME record for sub.example.com.example.com`,
+        Prints the CNAME record for sub.example.com.example.com
+    az-dns get MX @ -z example.com
+        Prints MX records for example.com, e.g. "10 mail.example.com"
+    az-dns get SOA @ -z example.com
+        Prints the SOA record for example.com
+    az-dns get TXT selector1._domainkey --raw -z example.com
+        Prints the TXT records for selector1._domainkey.example.com with each
+        of their strings quoted, e.g. "v=DKIM1; k=rsa; p=MIIB..." "...IDAQAB"`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		recordType := dns.RecordType(strings.ToUpper(args[0]))
 		hostname := args[1]
 
-		client, err := helpers.NewRecordSetClient(dns.DefaultBaseURI)
+		client, err := newRecordSetClient()
 		if err != nil {
 			return err
 		}
 
-		resourceGroup := viper.GetString("resource-group")
-		if resourceGroup == "" {
-			return fmt.Errorf("a resource group name is required")
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
 		}
 
-		zone := viper.GetString("zone")
-		if zone == "" {
-			return fmt.Errorf("a DNS zone name is required")
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
 		}
 
 		cmd.SilenceUsage = true
 
-		relative := viper.GetBool("relative")
 		recordName := helpers.GenerateRecordName(hostname, zone, relative)
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
 		rrset, err := client.Get(ctx, resourceGroup, zone, recordName, recordType)
-
 		if err != nil {
 			return err
 		}
 
-		switch recordType {
-		case dns.A:
-			if rrset.ARecords != nil {
-				for _, record := range *rrset.ARecords {
-					fmt.Println(*record.Ipv4Address)
-				}
-			}
-		case dns.AAAA:
-			if rrset.AaaaRecords != nil {
-				for _, record := range *rrset.AaaaRecords {
-					fmt.Println(*record.Ipv6Address)
-				}
-			}
-		case dns.CAA:
-			if rrset.CaaRecords != nil {
-				for _, record := range *rrset.CaaRecords {
-					fmt.Printf("%v %v %q\n", *record.Flags, *record.Tag, *record.Value)
-				}
-			}
-		case dns.CNAME:
-			if rrset.CnameRecord != nil {
-				record := *rrset.CnameRecord
-				fmt.Println(*record.Cname)
-			}
-		case dns.TXT:
-			if rrset.TxtRecords != nil {
-				for _, record := range *rrset.TxtRecords {
-					for _, line := range *record.Value {
-						fmt.Println(line)
-					}
-				}
-			}
-		default:
-			out, err := json.Marshal(rrset.RecordSetProperties)
-			if err != nil {
-				return err
+		if format == outputText && viper.GetBool("raw") {
+			for _, value := range helpers.RecordValues(recordType, rrset.RecordSetProperties) {
+				fmt.Println(value)
 			}
-			fmt.Printf("%s\n", out)
+			return nil
 		}
 
-		return nil
+		return printRecordSet(os.Stdout, format, rrset)
 	},
 }
 
@@ -114,6 +97,7 @@ func init() {
 	rootCmd.AddCommand(getCmd)
 
 	getCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	getCmd.PersistentFlags().Bool("raw", false, "Print TXT records as quoted strings, without joining them")
 	if err := viper.BindPFlags(getCmd.PersistentFlags()); err != nil {
 		// This shouldn't happen
 		panic(err)
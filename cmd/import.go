@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import a zone file into a DNS zone",
+	Long: `Create record sets in Azure DNS from a zone file
+
+This will read an RFC 1035 (BIND) master file and create a record set in the
+zone for each distinct name and type found in it. FILE may be - to read from
+standard input. The file's origin defaults to the zone, and the $ORIGIN, $TTL,
+and $INCLUDE directives are supported, as are multi-line records enclosed in
+parentheses.
+
+The SOA record and the NS records at the apex of the zone are managed by Azure
+DNS, so they are skipped. Records of types that Azure DNS cannot host cause the
+import to fail before any changes are made. If the records in a set have
+different TTLs, the lowest is used.
+
+By default, record sets that already exist in the zone are left untouched and
+cause the import to fail; pass --overwrite to replace them instead.
+
+Examples:
+    az-dns import example.com.zone -z example.com
+        Creates the records in example.com.zone in the zone example.com`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filename := args[0]
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		resourceGroup, zone, err := getZoneInfo(ctx, "", false)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		records, err := readZoneFile(filename, zone)
+		if err != nil {
+			return &validationError{err}
+		}
+
+		rrsets, err := groupZoneFileRecords(records)
+		if err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		limits := helpers.GetTTLLimits()
+		for _, rrset := range rrsets {
+			if err := limits.Check(*rrset.TTL); err != nil {
+				return &validationError{fmt.Errorf("%v record set %v: %v", helpers.RecordSetType(rrset), *rrset.Name, err)}
+			}
+		}
+
+		ifNoneMatch := "*"
+		if viper.GetBool("overwrite") {
+			ifNoneMatch = ""
+		}
+
+		results := []dns.RecordSet{}
+		for _, rrset := range rrsets {
+			recordType := helpers.RecordSetType(rrset)
+			result, err := client.CreateOrUpdate(ctx, resourceGroup, zone, *rrset.Name, recordType, rrset, "", ifNoneMatch)
+			if err != nil {
+				return fmt.Errorf("failed to create %v record set %v: %v", recordType, *rrset.Name, err)
+			}
+			results = append(results, result)
+		}
+
+		if format == outputText {
+			fmt.Println("success")
+			return nil
+		}
+
+		return printRecordSets(os.Stdout, format, results)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.PersistentFlags().Bool("overwrite", false, "Replace record sets that already exist")
+	if err := viper.BindPFlags(importCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// readZoneFile parses the named zone file, or standard input if filename is -.
+func readZoneFile(filename string, zone string) ([]helpers.ZoneFileRecord, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	return helpers.ParseZoneFile(r, filename, zone)
+}
+
+// groupZoneFileRecords combines records read from a zone file into record sets
+// by name and type, in the order in which each set first appears. SOA records
+// and NS records at the apex are skipped with a warning.
+func groupZoneFileRecords(records []helpers.ZoneFileRecord) ([]dns.RecordSet, error) {
+	type recordSetKey struct {
+		name       string
+		recordType dns.RecordType
+	}
+
+	keys := []recordSetKey{}
+	groups := map[recordSetKey][]helpers.ZoneFileRecord{}
+
+	for _, record := range records {
+		if record.Type == dns.SOA || (record.Type == dns.NS && record.Name == "@") {
+			fmt.Fprintf(os.Stderr, "%v: skipping %v record at the apex, which is managed by Azure DNS\n", record.Source, record.Type)
+			continue
+		}
+
+		key := recordSetKey{strings.ToLower(record.Name), record.Type}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], record)
+	}
+
+	rrsets := []dns.RecordSet{}
+	for _, key := range keys {
+		group := groups[key]
+		name := group[0].Name
+
+		ttl := group[0].TTL
+		for _, record := range group[1:] {
+			if record.TTL != ttl {
+				fmt.Fprintf(os.Stderr, "%v: TTL of %v differs from other %v records for %v\n", record.Source, record.TTL, key.recordType, name)
+			}
+			if record.TTL < ttl {
+				ttl = record.TTL
+			}
+		}
+
+		rrset, err := generateZoneFileRecordParams(key.recordType, ttl, group)
+		if err != nil {
+			return nil, err
+		}
+
+		rrset.Name = &name
+		resourceType := "Microsoft.Network/dnszones/" + string(key.recordType)
+		rrset.Type = &resourceType
+		rrsets = append(rrsets, *rrset)
+	}
+
+	return rrsets, nil
+}
+
+// generateZoneFileRecordParams creates the parameters for a record set from
+// records read from a zone file. Each TXT record becomes a single record whose
+// character strings are kept separate, with any that are too long split; other
+// types are handled as though their fields had been given on the command line.
+func generateZoneFileRecordParams(recordType dns.RecordType, ttl int64, records []helpers.ZoneFileRecord) (*dns.RecordSet, error) {
+	if recordType == dns.TXT {
+		txtRecords := []dns.TxtRecord{}
+		for _, record := range records {
+			if len(record.Fields) == 0 {
+				return nil, fmt.Errorf("%v: a TXT record must have at least one character string", record.Source)
+			}
+			txtRecords = append(txtRecords, dns.TxtRecord{Value: splitTxtStrings(record.Fields)})
+		}
+
+		return &dns.RecordSet{
+			RecordSetProperties: &dns.RecordSetProperties{
+				TTL:        &ttl,
+				TxtRecords: &txtRecords,
+			},
+		}, nil
+	}
+
+	fieldCount := recordFieldCount(recordType)
+	values := []string{}
+	for _, record := range records {
+		if fieldCount > 0 && len(record.Fields) != fieldCount {
+			return nil, fmt.Errorf("%v: a %v record must have %v fields, got %v", record.Source, recordType, fieldCount, len(record.Fields))
+		}
+		values = append(values, record.Fields...)
+	}
+
+	rrset, err := generateRecordParams(recordType, ttl, values)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", records[0].Source, err)
+	}
+
+	return rrset, nil
+}
+
+// recordFieldCount returns the number of fields that make up a single record of
+// the given type, or 0 if the type has no fixed number of fields.
+func recordFieldCount(recordType dns.RecordType) int {
+	switch recordType {
+	case dns.A, dns.AAAA, dns.CNAME, dns.NS, dns.PTR:
+		return 1
+	case dns.MX:
+		return 2
+	case dns.CAA:
+		return 3
+	case dns.SRV:
+		return 4
+	}
+
+	return 0
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list [TYPE]",
+	Short: "List the DNS record sets in a zone",
+	Long: `List the record sets in an Azure DNS zone
+
+This will print every record set in the zone, or only those of type TYPE if it
+is provided. Azure DNS returns record sets in pages; all pages are retrieved,
+and the --top flag controls how many record sets are requested at a time.
+
+Record sets can be filtered by name. The --name-suffix flag is passed to Azure
+DNS and restricts the listing to record sets whose names end with the given
+labels. The --name flag matches record names relative to the zone against a
+shell-style glob pattern. The --meta flag selects record sets by metadata: KEY
+requires the key to be set, KEY=VALUE requires it to have that value, and !KEY
+requires it not to be set. If --meta is repeated, every condition must hold. In
+text output, the results are printed as a table.
+
+Examples:
+    az-dns list -z example.com
+        Prints every record set in example.com
+    az-dns list TXT -z example.com
+        Prints every TXT record set in example.com
+    az-dns list --name-suffix sub -z example.com
+        Prints every record set below sub.example.com
+    az-dns list A --name 'web*' -z example.com
+        Prints A record sets whose names start with "web"
+    az-dns list --meta owner=web --meta '!expires' -z example.com
+        Prints record sets owned by web that have no expiry`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var recordType dns.RecordType
+		if len(args) > 0 {
+			recordType = dns.RecordType(strings.ToUpper(args[0]))
+		}
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		resourceGroup, zone, err := getZoneInfo(ctx, "", false)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+		if format == outputText {
+			format = outputTable
+		}
+
+		var top *int32
+		if pageSize := viper.GetInt("top"); pageSize < 0 {
+			return &validationError{fmt.Errorf("invalid page size %v must not be negative", pageSize)}
+		} else if pageSize > 0 {
+			top = to.Int32Ptr(int32(pageSize))
+		}
+
+		pattern := viper.GetString("name")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return &validationError{fmt.Errorf("invalid name pattern %q: %v", pattern, err)}
+		}
+
+		filter, err := helpers.ParseMetadataFilter(viper.GetStringSlice("meta"))
+		if err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		rrsets, err := helpers.ListRecordSets(ctx, client, resourceGroup, zone, recordType, top, viper.GetString("name-suffix"))
+		if err != nil {
+			return err
+		}
+
+		if pattern != "" {
+			rrsets = filterRecordSetsByName(rrsets, pattern)
+		}
+		if len(filter) > 0 {
+			rrsets = filterRecordSetsByMetadata(rrsets, filter)
+		}
+
+		return printRecordSets(os.Stdout, format, rrsets)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.PersistentFlags().String("name-suffix", "", "Only list record sets with names ending in this suffix")
+	listCmd.PersistentFlags().String("name", "", "Only list record sets with names matching this glob pattern")
+	listCmd.PersistentFlags().StringSlice("meta", nil, "Only list record sets whose metadata matches KEY, KEY=VALUE, or !KEY (may be repeated)")
+	listCmd.PersistentFlags().Int("top", 0, "Number of record sets to request per page (default chosen by Azure)")
+	if err := viper.BindPFlags(listCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// filterRecordSetsByName returns the record sets whose names match the glob
+// pattern. The pattern must already be known to be valid.
+func filterRecordSetsByName(rrsets []dns.RecordSet, pattern string) []dns.RecordSet {
+	filtered := []dns.RecordSet{}
+	for _, rrset := range rrsets {
+		if matched, _ := path.Match(pattern, to.String(rrset.Name)); matched {
+			filtered = append(filtered, rrset)
+		}
+	}
+
+	return filtered
+}
+
+// filterRecordSetsByMetadata returns the record sets whose metadata matches
+// filter.
+func filterRecordSetsByMetadata(rrsets []dns.RecordSet, filter helpers.MetadataFilter) []dns.RecordSet {
+	filtered := []dns.RecordSet{}
+	for _, rrset := range rrsets {
+		if filter.Matches(rrset) {
+			filtered = append(filtered, rrset)
+		}
+	}
+
+	return filtered
+}
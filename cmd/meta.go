@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// metaCmd represents the meta command
+var metaCmd = &cobra.Command{
+	Use:   "meta",
+	Short: "Manage DNS record set metadata",
+	Long: `Inspect and change the metadata of record sets in Azure DNS
+
+Azure DNS lets each record set carry metadata: a set of KEY=VALUE entries that
+do not affect DNS responses but can record information such as the owner of a
+record, the ticket for which it was created, or when it should be removed. Keys
+are not case-sensitive.
+
+These commands take TYPE and HOSTNAME as set does, and change only the metadata
+of an existing record set, leaving its records and TTL alone. Changes are made
+with the same conflict detection as add and remove. Metadata can also be given
+with "az-dns set --meta", and "az-dns list --meta" filters record sets by it.`,
+}
+
+func init() {
+	rootCmd.AddCommand(metaCmd)
+
+	metaCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	metaCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times to retry after a conflict")
+}
+
+// changeMetadata adds the entries in set to the metadata of the record set
+// identified by args, which are TYPE and HOSTNAME, and removes the keys in
+// unset. It prints the resulting record set, or with --dry-run, the change
+// that would be made.
+func changeMetadata(cmd *cobra.Command, args []string, set map[string]string, unset []string) error {
+	recordType := dns.RecordType(strings.ToUpper(args[0]))
+	hostname := args[1]
+
+	client, err := newRecordSetClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := newCommandContext()
+	defer cancel()
+
+	relative := viper.GetBool("relative")
+	resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+	if err != nil {
+		return err
+	}
+
+	format, err := getOutputFormat()
+	if err != nil {
+		return err
+	}
+
+	cmd.SilenceUsage = true
+
+	recordName := helpers.GenerateRecordName(hostname, zone, relative)
+	modify := metadataModifier(recordName, recordType, set, unset)
+	if isDryRun() {
+		return previewChange(ctx, os.Stdout, client, format, resourceGroup, zone, recordName, recordType, modify)
+	}
+
+	rrset, err := helpers.ModifyRecordSet(ctx, client, resourceGroup, zone, recordName, recordType,
+		viper.GetInt("conflict-retries"), modify)
+	if err != nil {
+		return err
+	}
+
+	if format == outputText {
+		fmt.Println("success")
+		return nil
+	}
+
+	return printRecordSet(os.Stdout, format, *rrset)
+}
+
+// metadataModifier returns a modifier that adds the entries in set to the
+// metadata of a record set and removes the keys in unset. The record set must
+// already exist.
+func metadataModifier(name string, recordType dns.RecordType, set map[string]string, unset []string) helpers.RecordSetModifier {
+	return func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		if current == nil || current.RecordSetProperties == nil {
+			return nil, &helpers.RecordSetNotFoundError{Name: name, Type: recordType}
+		}
+
+		existing := helpers.RecordSetMetadata(current)
+		metadata := helpers.UpdateMetadata(existing, set, unset)
+		if reflect.DeepEqual(existing, metadata) {
+			return current, nil
+		}
+
+		props := *current.RecordSetProperties
+		props.Metadata = helpers.MetadataPointers(metadata)
+		return &dns.RecordSet{Type: current.Type, RecordSetProperties: &props}, nil
+	}
+}
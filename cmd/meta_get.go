@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// metaGetCmd represents the meta get command
+var metaGetCmd = &cobra.Command{
+	Use:   "get TYPE HOSTNAME [KEY]",
+	Short: "Print the metadata of a DNS record set",
+	Long: `Print the metadata of a record set in Azure DNS
+
+This will print the metadata of a record set as KEY=VALUE lines sorted by key,
+or only the value of KEY if it is given. It is an error for KEY not to be set.
+The json and yaml formats print an object mapping keys to values.
+
+Examples:
+    az-dns meta get A www -z example.com
+        Prints every metadata entry of the A record set for www.example.com
+    az-dns meta get A www owner -z example.com
+        Prints the owner of the A record set for www.example.com`,
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recordType := dns.RecordType(strings.ToUpper(args[0]))
+		hostname := args[1]
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		recordName := helpers.GenerateRecordName(hostname, zone, relative)
+
+		rrset, err := client.Get(ctx, resourceGroup, zone, recordName, recordType)
+		if err != nil {
+			return err
+		}
+
+		metadata := helpers.RecordSetMetadata(&rrset)
+		if len(args) > 2 {
+			key := args[2]
+			value, ok := "", false
+			for k, v := range metadata {
+				if strings.EqualFold(k, key) {
+					key, value, ok = k, v, true
+				}
+			}
+			if !ok {
+				return fmt.Errorf("%v record set %v has no metadata key %q", recordType, recordName, args[2])
+			}
+			if format == outputText || format == outputZone {
+				_, err := fmt.Println(value)
+				return err
+			}
+			metadata = map[string]string{key: value}
+		}
+
+		return printMetadata(os.Stdout, format, metadata)
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaGetCmd)
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+)
+
+// metaSetCmd represents the meta set command
+var metaSetCmd = &cobra.Command{
+	Use:   "set TYPE HOSTNAME KEY=VALUE...",
+	Short: "Set metadata on a DNS record set",
+	Long: `Add or replace metadata entries on a record set in Azure DNS
+
+This will set each KEY to VALUE in the metadata of an existing record set,
+replacing any entry with the same key and keeping the others. The record set's
+records and TTL are not changed.
+
+Examples:
+    az-dns meta set A www owner=web ticket=OPS-123 -z example.com
+        Records the owner and ticket of the A record set for www.example.com
+    az-dns meta set TXT _dmarc expires=2027-01-01 -z example.com
+        Marks the TXT record set for _dmarc.example.com as expiring`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		metadata, err := helpers.ParseMetadata(args[2:])
+		if err != nil {
+			return &validationError{err}
+		}
+
+		return changeMetadata(cmd, args, metadata, nil)
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaSetCmd)
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// metaUnsetCmd represents the meta unset command
+var metaUnsetCmd = &cobra.Command{
+	Use:   "unset TYPE HOSTNAME KEY...",
+	Short: "Remove metadata from a DNS record set",
+	Long: `Remove metadata entries from a record set in Azure DNS
+
+This will remove each KEY from the metadata of an existing record set. Keys
+that are not set are ignored. The record set's records and TTL are not changed.
+
+Examples:
+    az-dns meta unset A www ticket -z example.com
+        Removes the ticket from the A record set for www.example.com`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeMetadata(cmd, args, nil, args[2:])
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaUnsetCmd)
+}
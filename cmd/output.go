@@ -0,0 +1,373 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Supported values for the --output flag.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputTable = "table"
+	outputZone  = "zone"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputYAML, outputTable, outputZone}
+
+// recordSetOutput is the representation of a record set emitted by the json
+// and yaml output formats. Records are in zone file presentation format.
+type recordSetOutput struct {
+	Name     string            `json:"name" yaml:"name"`
+	FQDN     string            `json:"fqdn" yaml:"fqdn"`
+	Type     string            `json:"type" yaml:"type"`
+	TTL      int64             `json:"ttl" yaml:"ttl"`
+	Etag     string            `json:"etag,omitempty" yaml:"etag,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Records  []string          `json:"records" yaml:"records"`
+}
+
+// zoneOutput is the representation of a DNS zone emitted by the json and yaml
+// output formats.
+type zoneOutput struct {
+	Name                  string            `json:"name" yaml:"name"`
+	ResourceGroup         string            `json:"resourceGroup" yaml:"resourceGroup"`
+	Etag                  string            `json:"etag,omitempty" yaml:"etag,omitempty"`
+	NameServers           []string          `json:"nameServers" yaml:"nameServers"`
+	NumberOfRecordSets    int64             `json:"numberOfRecordSets" yaml:"numberOfRecordSets"`
+	MaxNumberOfRecordSets int64             `json:"maxNumberOfRecordSets" yaml:"maxNumberOfRecordSets"`
+	Tags                  map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// resultOutput is the representation of a completed operation that does not
+// produce a record set, such as a deletion.
+type resultOutput struct {
+	Name   string `json:"name" yaml:"name"`
+	Zone   string `json:"zone" yaml:"zone"`
+	Type   string `json:"type" yaml:"type"`
+	Result string `json:"result" yaml:"result"`
+}
+
+// batchResultOutput is the outcome of a single operation in a batch.
+type batchResultOutput struct {
+	Line      int    `json:"line" yaml:"line"`
+	Operation string `json:"operation" yaml:"operation"`
+	Name      string `json:"name" yaml:"name"`
+	Zone      string `json:"zone" yaml:"zone"`
+	Type      string `json:"type" yaml:"type"`
+	Result    string `json:"result" yaml:"result"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// getOutputFormat returns the output format requested with the --output flag,
+// or an error if it is not supported.
+func getOutputFormat() (string, error) {
+	format := strings.ToLower(viper.GetString("output"))
+	for _, supported := range outputFormats {
+		if format == supported {
+			return format, nil
+		}
+	}
+
+	return "", &validationError{fmt.Errorf("unsupported output format %q, must be one of: %v", format, strings.Join(outputFormats, ", "))}
+}
+
+func newRecordSetOutput(rrset dns.RecordSet) recordSetOutput {
+	recordType := helpers.RecordSetType(rrset)
+	output := recordSetOutput{
+		Name:    to.String(rrset.Name),
+		Type:    string(recordType),
+		Etag:    to.String(rrset.Etag),
+		Records: helpers.RecordValues(recordType, rrset.RecordSetProperties),
+	}
+
+	if props := rrset.RecordSetProperties; props != nil {
+		output.FQDN = to.String(props.Fqdn)
+		output.TTL = to.Int64(props.TTL)
+		if props.Metadata != nil {
+			output.Metadata = to.StringMap(*props.Metadata)
+		}
+	}
+
+	return output
+}
+
+func newZoneOutput(zone dns.Zone) zoneOutput {
+	output := zoneOutput{
+		Name:          to.String(zone.Name),
+		ResourceGroup: helpers.ResourceGroupFromID(to.String(zone.ID)),
+		Etag:          to.String(zone.Etag),
+		NameServers:   []string{},
+	}
+
+	if zone.Tags != nil {
+		output.Tags = to.StringMap(*zone.Tags)
+	}
+
+	if props := zone.ZoneProperties; props != nil {
+		output.NameServers = append(output.NameServers, to.StringSlice(props.NameServers)...)
+		output.NumberOfRecordSets = to.Int64(props.NumberOfRecordSets)
+		output.MaxNumberOfRecordSets = to.Int64(props.MaxNumberOfRecordSets)
+	}
+
+	return output
+}
+
+// printRecordSet writes a single record set to w in the given format. The text
+// format prints one record per line, with TXT records printed unquoted and the
+// character strings of each joined together.
+func printRecordSet(w io.Writer, format string, rrset dns.RecordSet) error {
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, newRecordSetOutput(rrset))
+	case outputText:
+		recordType := helpers.RecordSetType(rrset)
+		if recordType == dns.TXT && rrset.RecordSetProperties != nil && rrset.TxtRecords != nil {
+			for _, record := range *rrset.TxtRecords {
+				fmt.Fprintln(w, strings.Join(to.StringSlice(record.Value), ""))
+			}
+			return nil
+		}
+
+		for _, value := range helpers.RecordValues(recordType, rrset.RecordSetProperties) {
+			fmt.Fprintln(w, value)
+		}
+		return nil
+	}
+
+	return printRecordSets(w, format, []dns.RecordSet{rrset})
+}
+
+// printRecordSets writes a collection of record sets to w in the given format.
+// The json and yaml formats always produce a list, even if it is empty.
+func printRecordSets(w io.Writer, format string, rrsets []dns.RecordSet) error {
+	switch format {
+	case outputJSON, outputYAML:
+		outputs := []recordSetOutput{}
+		for _, rrset := range rrsets {
+			outputs = append(outputs, newRecordSetOutput(rrset))
+		}
+		return printStructured(w, format, outputs)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTYPE\tTTL\tRECORD")
+		for _, rrset := range rrsets {
+			output := newRecordSetOutput(rrset)
+			for _, record := range output.Records {
+				fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", output.Name, output.Type, output.TTL, record)
+			}
+		}
+		return tw.Flush()
+	case outputZone:
+		for _, rrset := range rrsets {
+			output := newRecordSetOutput(rrset)
+			owner := output.FQDN
+			if owner == "" {
+				owner = output.Name
+			}
+			recordType := dns.RecordType(output.Type)
+			for _, record := range helpers.ZoneFileValues(recordType, rrset.RecordSetProperties) {
+				fmt.Fprintf(w, "%v\t%v\tIN\t%v\t%v\n", owner, output.TTL, output.Type, record)
+			}
+		}
+		return nil
+	}
+
+	for _, rrset := range rrsets {
+		if err := printRecordSet(w, format, rrset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printMetadata writes the metadata of a record set to w in the given format.
+// The json and yaml formats print an object, the table format prints a KEY and
+// VALUE column, and other formats print KEY=VALUE lines, all sorted by key.
+func printMetadata(w io.Writer, format string, metadata map[string]string) error {
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, metadata)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		for _, entry := range helpers.FormatMetadata(metadata) {
+			fmt.Fprintln(tw, strings.Replace(entry, "=", "\t", 1))
+		}
+		return tw.Flush()
+	}
+
+	for _, entry := range helpers.FormatMetadata(metadata) {
+		if _, err := fmt.Fprintln(w, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printZones writes a collection of DNS zones to w in the given format. The
+// text and table formats print one zone per line, showing the number of record
+// sets in use against the zone's limit.
+func printZones(w io.Writer, format string, zones []dns.Zone) error {
+	outputs := []zoneOutput{}
+	for _, zone := range zones {
+		outputs = append(outputs, newZoneOutput(zone))
+	}
+
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, outputs)
+	case outputZone:
+		for _, output := range outputs {
+			for _, nameServer := range output.NameServers {
+				fmt.Fprintf(w, "%v.\tIN\tNS\t%v\n", output.Name, nameServer)
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tRESOURCE GROUP\tRECORD SETS\tNAME SERVERS")
+	for _, output := range outputs {
+		fmt.Fprintf(tw, "%v\t%v\t%v/%v\t%v\n", output.Name, output.ResourceGroup, output.NumberOfRecordSets,
+			output.MaxNumberOfRecordSets, strings.Join(output.NameServers, ","))
+	}
+	return tw.Flush()
+}
+
+// printZone writes a single DNS zone to w in the given format. The text format
+// prints each of the zone's properties on its own line, followed by its name
+// servers.
+func printZone(w io.Writer, format string, zone dns.Zone) error {
+	output := newZoneOutput(zone)
+
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, output)
+	case outputText:
+		fmt.Fprintf(w, "name: %v\n", output.Name)
+		fmt.Fprintf(w, "resource group: %v\n", output.ResourceGroup)
+		fmt.Fprintf(w, "record sets: %v/%v\n", output.NumberOfRecordSets, output.MaxNumberOfRecordSets)
+		fmt.Fprintln(w, "name servers:")
+		for _, nameServer := range output.NameServers {
+			fmt.Fprintf(w, "    %v\n", nameServer)
+		}
+		return nil
+	}
+
+	return printZones(w, format, []dns.Zone{zone})
+}
+
+// printResult reports the outcome of an operation on the named record set. The
+// text format simply prints "success".
+func printResult(w io.Writer, format string, result resultOutput) error {
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, result)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tZONE\tTYPE\tRESULT")
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", result.Name, result.Zone, result.Type, result.Result)
+		return tw.Flush()
+	case outputZone:
+		_, err := fmt.Fprintf(w, "; %v %v %v\n", result.Name, result.Type, result.Result)
+		return err
+	}
+
+	_, err := fmt.Fprintln(w, "success")
+	return err
+}
+
+// printResults reports the outcomes of operations on several record sets. The
+// json and yaml formats always produce a list, even if it is empty, and the
+// text format prints "success" once.
+func printResults(w io.Writer, format string, results []resultOutput) error {
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, results)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tZONE\tTYPE\tRESULT")
+		for _, result := range results {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", result.Name, result.Zone, result.Type, result.Result)
+		}
+		return tw.Flush()
+	case outputZone:
+		for _, result := range results {
+			if err := printResult(w, format, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err := fmt.Fprintln(w, "success")
+	return err
+}
+
+// printBatchResults reports the outcomes of the operations in a batch. The json
+// and yaml formats always produce a list, even if it is empty, and the text
+// format prints one line per operation.
+func printBatchResults(w io.Writer, format string, results []batchResultOutput) error {
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(w, format, results)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "LINE\tOPERATION\tNAME\tZONE\tTYPE\tRESULT\tERROR")
+		for _, result := range results {
+			fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", result.Line, result.Operation, result.Name, result.Zone,
+				result.Type, result.Result, result.Error)
+		}
+		return tw.Flush()
+	}
+
+	prefix := ""
+	if format == outputZone {
+		prefix = "; "
+	}
+
+	for _, result := range results {
+		message := result.Result
+		if result.Error != "" {
+			message = fmt.Sprintf("%v: %v", result.Result, result.Error)
+		}
+		if _, err := fmt.Fprintf(w, "%vline %v: %v %v %v: %v\n", prefix, result.Line, result.Operation, result.Type,
+			result.Name, message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printStructured marshals value as JSON or YAML and writes it to w.
+func printStructured(w io.Writer, format string, value interface{}) error {
+	var out []byte
+	var err error
+
+	if format == outputYAML {
+		out, err = yaml.Marshal(value)
+	} else {
+		out, err = json.MarshalIndent(value, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultDesiredTTL is the TTL given to record sets in a desired-state file
+// when neither the record set nor the file specifies one.
+const defaultDesiredTTL = 300
+
+// desiredState is the contents of a desired-state file. Record sets use the
+// same fields as the json and yaml output formats, so the output of list may be
+// used as a starting point.
+type desiredState struct {
+	Zone          string            `json:"zone" yaml:"zone"`
+	ResourceGroup string            `json:"resourceGroup" yaml:"resourceGroup"`
+	TTL           int64             `json:"ttl" yaml:"ttl"`
+	Records       []recordSetOutput `json:"records" yaml:"records"`
+}
+
+// changeOutput is the representation of a planned change emitted by the json
+// and yaml output formats.
+type changeOutput struct {
+	Action  string           `json:"action" yaml:"action"`
+	Name    string           `json:"name" yaml:"name"`
+	Type    string           `json:"type" yaml:"type"`
+	Current *recordSetOutput `json:"current,omitempty" yaml:"current,omitempty"`
+	Desired *recordSetOutput `json:"desired,omitempty" yaml:"desired,omitempty"`
+}
+
+// planOutput is the representation of a plan emitted by the json and yaml
+// output formats.
+type planOutput struct {
+	Zone          string         `json:"zone" yaml:"zone"`
+	ResourceGroup string         `json:"resourceGroup" yaml:"resourceGroup"`
+	Changes       []changeOutput `json:"changes" yaml:"changes"`
+}
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan FILE",
+	Short: "Show the changes needed to bring a zone to its desired state",
+	Long: `Compare a DNS zone with a desired-state file and show the differences
+
+This will read a YAML or JSON document describing the record sets that should
+exist in a zone and compare it with the zone's current contents, printing the
+record sets that would be created, updated, or deleted by apply. FILE may be -
+to read from standard input. No changes are made.
+
+The document has the following form:
+    zone: example.com
+    resourceGroup: dns
+    ttl: 3600
+    records:
+    - name: www
+      type: CNAME
+      records: [example.com]
+    - name: "@"
+      type: MX
+      ttl: 300
+      metadata: {owner: mail}
+      records: ["10 mail1.example.com", "20 mail2.example.com"]
+
+The zone and resource group are used only if they are not given on the command
+line. Record names are relative to the zone unless they end with a dot. Records
+are written in zone file presentation format, and record sets without a TTL
+use the document's ttl, or 300 if it has none. A record set is only compared
+on its metadata if the document specifies metadata for it. The output of
+"az-dns list -o yaml", which is a bare list of record sets, is also accepted.
+
+Record sets in the zone that are not in the document are left alone unless
+--prune is given. The SOA record set and the NS record set at the apex are
+managed by Azure DNS and are never deleted.
+
+With the text output format, each change is listed with the records that are
+added (+) or removed (-); the json and yaml formats list the current and
+desired state of each changed record set.
+
+Examples:
+    az-dns plan example.com.yaml
+        Shows the changes needed to make example.com match example.com.yaml
+    az-dns plan example.com.yaml --prune
+        Also shows the record sets that are not in example.com.yaml and would
+        be deleted`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		state, err := readDesiredState(args[0])
+		if err != nil {
+			return &validationError{err}
+		}
+
+		resourceGroup, zone, err := getDesiredZoneInfo(state)
+		if err != nil {
+			return err
+		}
+
+		desired, err := desiredRecordSets(args[0], zone, state)
+		if err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		current, err := helpers.ListRecordSets(ctx, client, resourceGroup, zone, "", nil, "")
+		if err != nil {
+			return err
+		}
+
+		changes := helpers.DiffRecordSets(current, desired, viper.GetBool("prune"))
+		if err := checkChangeTTLs(helpers.GetTTLLimits(), changes); err != nil {
+			return err
+		}
+		return printPlan(os.Stdout, format, resourceGroup, zone, changes)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	planCmd.PersistentFlags().Bool("prune", false, "Include record sets that are not in the desired state")
+	if err := viper.BindPFlags(planCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// readDesiredState reads a desired-state document from the named file, or from
+// standard input if filename is -. A document that is just a list of record
+// sets is treated as though it had been given as records.
+func readDesiredState(filename string) (*desiredState, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML, so a single decoder handles both.
+	state := &desiredState{}
+	if err := yaml.Unmarshal(contents, state); err != nil {
+		records := []recordSetOutput{}
+		if listErr := yaml.Unmarshal(contents, &records); listErr != nil {
+			return nil, fmt.Errorf("%v: %v", filename, err)
+		}
+		state.Records = records
+	}
+
+	return state, nil
+}
+
+// getDesiredZoneInfo returns the names of the resource group and DNS zone on
+// which a desired-state document should be applied. Names given on the command
+// line take precedence over those in the document.
+func getDesiredZoneInfo(state *desiredState) (resourceGroup string, zone string, err error) {
+	resourceGroup = viper.GetString("resource-group")
+	if resourceGroup == "" {
+		resourceGroup = state.ResourceGroup
+	}
+	if resourceGroup == "" {
+		return "", "", &validationError{fmt.Errorf("a resource group name is required")}
+	}
+
+	zone = viper.GetString("zone")
+	if zone == "" {
+		zone = strings.TrimRight(state.Zone, ".")
+	}
+	if zone == "" {
+		return "", "", &validationError{fmt.Errorf("a DNS zone name is required")}
+	}
+
+	return resourceGroup, zone, nil
+}
+
+// desiredRecordSets converts the record sets in a desired-state document into
+// the parameters that would be used to create them. Record set names are made
+// relative to zone, and a record set may only appear once.
+func desiredRecordSets(filename string, zone string, state *desiredState) ([]dns.RecordSet, error) {
+	type recordSetKey struct {
+		name       string
+		recordType dns.RecordType
+	}
+
+	defaultTTL := state.TTL
+	if defaultTTL == 0 {
+		defaultTTL = defaultDesiredTTL
+	}
+
+	seen := map[recordSetKey]bool{}
+	types := map[string][]dns.RecordType{}
+	rrsets := []dns.RecordSet{}
+
+	for _, desired := range state.Records {
+		recordType := dns.RecordType(strings.ToUpper(desired.Type))
+		name := helpers.GenerateRecordName(desired.Name, zone, !strings.HasSuffix(desired.Name, "."))
+		source := fmt.Sprintf("%v: %v %v", filename, name, recordType)
+
+		if recordType == dns.SOA {
+			return nil, fmt.Errorf("%v: the SOA record is managed by Azure DNS", source)
+		}
+
+		if err := checkRecordName(name, recordType); err != nil {
+			return nil, fmt.Errorf("%v: %v", source, err)
+		}
+
+		key := recordSetKey{strings.ToLower(name), recordType}
+		if seen[key] {
+			return nil, fmt.Errorf("%v: record set appears more than once", source)
+		}
+		seen[key] = true
+		if err := helpers.ValidateCNAMEPlacement(name, recordType, types[key.name]); err != nil {
+			return nil, fmt.Errorf("%v: %v", source, err)
+		}
+		types[key.name] = append(types[key.name], recordType)
+
+		if len(desired.Records) == 0 {
+			return nil, fmt.Errorf("%v: record set has no records", source)
+		}
+
+		records := []helpers.ZoneFileRecord{}
+		for _, value := range desired.Records {
+			fields, err := helpers.ParseRecordFields(recordType, value)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", source, err)
+			}
+			records = append(records, helpers.ZoneFileRecord{
+				Name:   name,
+				Type:   recordType,
+				Fields: fields,
+				Source: source,
+			})
+		}
+
+		ttl := desired.TTL
+		if ttl == 0 {
+			ttl = defaultTTL
+		}
+
+		rrset, err := generateZoneFileRecordParams(recordType, ttl, records)
+		if err != nil {
+			return nil, err
+		}
+
+		if desired.Metadata != nil {
+			metadata := map[string]*string{}
+			for key, value := range desired.Metadata {
+				metadata[key] = to.StringPtr(value)
+			}
+			rrset.Metadata = &metadata
+		}
+
+		rrsetName := name
+		resourceType := "Microsoft.Network/dnszones/" + string(recordType)
+		rrset.Name = &rrsetName
+		rrset.Type = &resourceType
+		rrsets = append(rrsets, *rrset)
+	}
+
+	return rrsets, nil
+}
+
+// printPlan writes the changes needed to bring a zone to its desired state to w
+// in the given format. Formats other than json and yaml use the text format.
+func printPlan(w io.Writer, format string, resourceGroup string, zone string, changes []helpers.RecordSetChange) error {
+	if format == outputJSON || format == outputYAML {
+		output := planOutput{
+			Zone:          zone,
+			ResourceGroup: resourceGroup,
+			Changes:       []changeOutput{},
+		}
+
+		for _, change := range changes {
+			changeOut := changeOutput{
+				Action: string(change.Action),
+				Name:   change.Name,
+				Type:   string(change.Type),
+			}
+			if change.Current != nil {
+				current := newRecordSetOutput(*change.Current)
+				changeOut.Current = &current
+			}
+			if change.Desired != nil {
+				desired := newRecordSetOutput(*change.Desired)
+				changeOut.Desired = &desired
+			}
+			output.Changes = append(output.Changes, changeOut)
+		}
+
+		return printStructured(w, format, output)
+	}
+
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No changes. The zone matches the desired state.")
+		return err
+	}
+
+	counts := map[helpers.ChangeAction]int{}
+	for _, change := range changes {
+		counts[change.Action]++
+		printChange(w, change)
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %v to create, %v to update, %v to delete.\n",
+		counts[helpers.ChangeCreate], counts[helpers.ChangeUpdate], counts[helpers.ChangeDelete])
+	return err
+}
+
+// printChange writes a single change in the text plan format: a line naming the
+// record set, prefixed with +, ~, or - for creation, update, or deletion,
+// followed by its TTL, metadata, and records as they would change.
+func printChange(w io.Writer, change helpers.RecordSetChange) {
+	symbols := map[helpers.ChangeAction]string{
+		helpers.ChangeCreate: "+",
+		helpers.ChangeUpdate: "~",
+		helpers.ChangeDelete: "-",
+	}
+	fmt.Fprintf(w, "%v %v %v\n", symbols[change.Action], change.Name, change.Type)
+
+	var current, desired recordSetOutput
+	if change.Current != nil {
+		current = newRecordSetOutput(*change.Current)
+		current.Records = helpers.ZoneFileValues(change.Type, change.Current.RecordSetProperties)
+	}
+	if change.Desired != nil {
+		desired = newRecordSetOutput(*change.Desired)
+		desired.Records = helpers.ZoneFileValues(change.Type, change.Desired.RecordSetProperties)
+	}
+
+	switch change.Action {
+	case helpers.ChangeCreate:
+		fmt.Fprintf(w, "    ttl: %v\n", desired.TTL)
+	case helpers.ChangeUpdate:
+		if current.TTL != desired.TTL {
+			fmt.Fprintf(w, "    ttl: %v -> %v\n", current.TTL, desired.TTL)
+		}
+	}
+
+	if change.Desired != nil && change.Desired.Metadata != nil && !reflect.DeepEqual(current.Metadata, desired.Metadata) {
+		fmt.Fprintf(w, "    metadata: %v -> %v\n", formatMetadata(current.Metadata), formatMetadata(desired.Metadata))
+	}
+
+	currentRecords := map[string]bool{}
+	for _, record := range current.Records {
+		currentRecords[record] = true
+	}
+	desiredRecords := map[string]bool{}
+	for _, record := range desired.Records {
+		desiredRecords[record] = true
+	}
+
+	for _, record := range current.Records {
+		if !desiredRecords[record] {
+			fmt.Fprintf(w, "    - %v\n", record)
+		}
+	}
+	for _, record := range desired.Records {
+		if !currentRecords[record] {
+			fmt.Fprintf(w, "    + %v\n", record)
+		}
+	}
+}
+
+// formatMetadata returns metadata as a sorted, comma-separated list of
+// key=value pairs enclosed in braces.
+func formatMetadata(metadata map[string]string) string {
+	return "{" + strings.Join(helpers.FormatMetadata(metadata), ", ") + "}"
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// removeCmd represents the remove command
+var removeCmd = &cobra.Command{
+	Use:   "remove TYPE HOSTNAME VALUES",
+	Short: "Remove records from a DNS record set",
+	Long: `Remove records from a record set in Azure DNS
+
+This will remove the given records from a record set while leaving any others
+in it alone. If no records remain, the record set is deleted. Records that are
+not present are ignored, as is a record set that does not exist. VALUES and
+HOSTNAME are given exactly as they are for set; see "az-dns set --help" for
+details.
+
+Like add, remove reads the record set and writes it back only if it has not
+been changed in the meantime, retrying with backoff if it has, so it is safe
+to use when several clients share a record set. If the retries run out, az-dns
+exits with status 5.
+
+With --wait, remove does not return until the zone's name servers no longer
+serve the removed records; see "az-dns wait --help" for details.
+
+Examples:
+    az-dns remove TXT _acme-challenge token -z example.com
+        Removes "token" from the TXT records for _acme-challenge.example.com,
+        deleting the record set if it was the only one
+    az-dns remove MX @ 20 mail2.example.com -z example.com
+        Removes the record "20 mail2.example.com" from the MX records at the
+        apex of example.com`,
+	Args: cobra.MinimumNArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recordType := dns.RecordType(strings.ToUpper(args[0]))
+		hostname := args[1]
+		records := args[2:]
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		recordName := helpers.GenerateRecordName(hostname, zone, relative)
+
+		removals, err := generateArgsRecordParams(recordType, 0, records)
+		if err != nil {
+			return &validationError{err}
+		}
+
+		cmd.SilenceUsage = true
+
+		modify := removeRecordsModifier(recordType, removals)
+		if isDryRun() {
+			return previewChange(ctx, os.Stdout, client, format, resourceGroup, zone, recordName, recordType, modify)
+		}
+
+		rrset, err := helpers.ModifyRecordSet(ctx, client, resourceGroup, zone, recordName, recordType,
+			viper.GetInt("conflict-retries"), modify)
+		if err != nil {
+			return err
+		}
+
+		if viper.GetBool("wait") {
+			if err := waitForPropagation(ctx, cmd.Flags(), resourceGroup, zone, recordName, recordType, removals.RecordSetProperties, false); err != nil {
+				return err
+			}
+		}
+
+		if format == outputText {
+			fmt.Println("success")
+			return nil
+		}
+
+		if rrset == nil {
+			return printResult(os.Stdout, format, resultOutput{
+				Name:   recordName,
+				Zone:   zone,
+				Type:   string(recordType),
+				Result: "deleted",
+			})
+		}
+
+		return printRecordSet(os.Stdout, format, *rrset)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	removeCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	removeCmd.PersistentFlags().Bool("quoted", false, "Each TXT value is one or more quoted strings, as in a zone file")
+	removeCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times to retry after a conflict")
+	addWaitFlags(removeCmd.PersistentFlags())
+	if err := viper.BindPFlags(removeCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// removeRecordsModifier returns a modifier that removes the records in removals
+// from a record set, deleting it if no records remain.
+func removeRecordsModifier(recordType dns.RecordType, removals *dns.RecordSet) helpers.RecordSetModifier {
+	return func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		if current == nil {
+			return nil, nil
+		}
+
+		props, remaining := helpers.RemoveRecords(recordType, current.RecordSetProperties, removals.RecordSetProperties)
+		if remaining == 0 {
+			return nil, nil
+		}
+
+		desired := &dns.RecordSet{Type: current.Type, RecordSetProperties: props}
+		if helpers.RecordSetsEqual(*current, *desired) {
+			return current, nil
+		}
+
+		return desired, nil
+	}
+}
@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/elyscape/az-dns/helpers"
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -20,39 +25,186 @@ var rootCmd = &cobra.Command{
 	Long: `A simple command-line tool for manipulating Azure DNS record sets
 
 This client provides an easy way to view and manipulate record sets in Azure
-DNS. It authenticates as an Azure Active Directory service principal, using
-credentials provided via:
+DNS. It authenticates to Azure Active Directory using credentials provided via:
     a. command-line flags
     b. environment variables
     c. a config file, or
-    d. an Azure CLI auth file, with path specified in $AZURE_AUTH_LOCATION`,
+    d. an Azure CLI auth file, with path specified in $AZURE_AUTH_LOCATION
+
+The --auth-method flag selects how to authenticate:
+    auth-file           an Azure CLI auth file
+    client-secret       a service principal with --client-secret
+    client-certificate  a service principal with the certificate and private
+                        key in the PEM file given by --client-certificate
+    federated-token     a service principal with a federated token, such as
+                        one for AKS workload identity, read from the file given
+                        by --federated-token-file
+    msi                 a managed identity, which is user-assigned if
+                        --client-id is given
+    cli                 the account logged in to the Azure CLI, whose current
+                        subscription is used if --subscription-id is not given
+By default, each method for which options are present is tried in that order,
+and the first that obtains an access token is used; with --verbose, those that
+fail are logged. Managed identities are tried when --msi-endpoint is given or
+the Azure Instance Metadata Service responds, as it does on VMs, scale sets,
+and AKS nodes, and the Azure CLI when az can be found.
+
+The --cloud flag selects the Azure cloud, which determines the Azure AD and
+Azure Resource Manager endpoints that are used:
+    AzurePublicCloud        the global Azure cloud (the default)
+    AzureChinaCloud         Azure China
+    AzureUSGovernmentCloud  Azure US Government
+    AzureGermanCloud        Azure Germany
+    AzureStackCloud         the environment in the JSON file given by
+                            $AZURE_ENVIRONMENT_FILEPATH, such as an Azure Stack
+                            instance
+    PATH                    the environment in the JSON file at PATH
+Cloud names are not case-sensitive and may omit the leading Azure and trailing
+Cloud, as in --cloud china. When an auth file is used, its endpoints take
+precedence.
+
+Commands that take a HOSTNAME can find the zone containing it when --zone is
+not given, by choosing the zone in the subscription, or in the resource group
+given by --resource-group, whose name is the longest suffix of HOSTNAME. This
+requires HOSTNAME to be a fully-qualified domain name. Likewise, if --zone is
+given without --resource-group, the resource group is found from the zone. The
+zones found are cached for --zone-cache-ttl so that repeated commands do not
+need to list them again.
+
+Requests to Azure that fail because of a connection error, throttling, or a
+server error are retried up to --max-retries times, waiting as long as Azure
+asks or otherwise backing off exponentially, but giving up once a request has
+taken --retry-deadline. Changes are only retried after throttling or when
+made with --if-match or --if-none-match, as they may have been made despite
+failing otherwise. Requests are also slowed down when Azure reports that
+few remain before throttling begins. With --verbose, each retry is logged.
+
+Record sets keep their TTL when their records are changed unless --ttl is
+given. To guard against mistakes, min-ttl and max-ttl limit the TTLs that
+commands may give record sets, and are best set for each zone in the config
+file; see "az-dns config --help" for details. A record set whose TTL is not
+being changed is not checked against them.
+
+--timeout limits the time a command may take as a whole. A command that runs
+out of time exits with status 7, and one interrupted by SIGINT or SIGTERM
+stops its requests and exits with status 128 plus the signal number.
+
+With --dry-run, set, add, remove, and clear read the record set and print the
+change they would make as a unified diff of its records in zone file
+presentation format, with metadata as comments, instead of making it. The json
+and yaml formats print the current and desired record sets instead. Other
+commands that would change a zone fail before changing anything.
+
+When a command fails, the exit status describes why:
+    1   any other failure
+    2   invalid arguments, flags, or input
+    3   the zone or record set does not exist
+    4   credentials are missing or invalid, or access was denied
+    5   a conflicting change was made concurrently
+    6   Azure throttled the requests
+    7   the command took longer than --timeout
+With --output json or yaml, the error is written to stderr in the same format,
+as an object with kind, message, exitCode, and, for errors returned by Azure,
+statusCode, code, and requestId.
+
+By default, commands print results in a simple text format intended for
+shell scripts. The --output flag selects a different format:
+    text    record values, one per line, or "success" for changes
+    json    JSON objects with name, fqdn, type, ttl, etag, metadata, and records
+    yaml    the same fields as json, formatted as YAML
+    table   an aligned table with one row per record
+    zone    RFC 1035 zone file presentation format`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Several commands define flags with the same name, and viper only
+		// remembers the last flag bound to each key, so bind the flags of the
+		// command being run.
+		if err := viper.BindPFlags(cmd.Flags()); err != nil {
+			return err
+		}
+
+		// Errors are reported in the requested format by Execute.
+		if format, err := getOutputFormat(); err == nil && (format == outputJSON || format == outputYAML) {
+			cmd.Root().SilenceErrors = true
+		}
+
+		startCommandContext()
+
+		if configError != nil {
+			cmd.SilenceUsage = true
+			return &validationError{configError}
+		}
+
+		return applyZoneDefaults(viper.GetString("zone"))
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags
 // appropriately. This is called by main.main(). It only needs to happen once
 // to the rootCmd.
 func Execute() {
+	reportCommandErrors(rootCmd)
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		// Errors that did not come from a command, such as unknown flags,
+		// are usage errors.
+		f := newFailure(err, true)
+		if rootCmd.SilenceErrors {
+			reportFailure(os.Stderr, f)
+		}
+		os.Exit(f.ExitCode)
 	}
 }
 
+// Exit statuses returned by az-dns when a command fails. A command interrupted
+// by a signal exits with 128 plus the signal's number.
+const (
+	exitFailure   = 1
+	exitUsage     = 2
+	exitNotFound  = 3
+	exitAuth      = 4
+	exitConflict  = 5
+	exitThrottled = 6
+	exitTimeout   = 7
+)
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Config file (default is $HOME/.az-dns.yaml)")
+	rootCmd.PersistentFlags().String("profile", "", "Name of the config file profile to use")
 
 	// credentials
 	rootCmd.PersistentFlags().String("client-id", "", "Azure client ID")
 	rootCmd.PersistentFlags().String("client-secret", "", "Azure client secret")
 	rootCmd.PersistentFlags().String("tenant-id", "", "Azure tenant ID")
 	rootCmd.PersistentFlags().String("subscription-id", "", "Azure subscription ID")
+	rootCmd.PersistentFlags().String("auth-method", helpers.AuthMethodAuto, "Authentication method ("+strings.Join(helpers.AuthMethods, ", ")+")")
+	rootCmd.PersistentFlags().String("client-certificate", "", "Path to a PEM file with an Azure client certificate and private key")
+	rootCmd.PersistentFlags().String("client-certificate-password", "", "Password for an encrypted client certificate private key")
+	rootCmd.PersistentFlags().String("federated-token-file", "", "Path to a file containing a federated token for the Azure client")
+	rootCmd.PersistentFlags().String("msi-endpoint", "", "Azure managed identity token endpoint (default the Instance Metadata Service)")
+
+	// cloud
+	rootCmd.PersistentFlags().String("cloud", "", "Azure cloud name, such as AzureChinaCloud, or path to an environment file (default AzurePublicCloud)")
+	rootCmd.PersistentFlags().String("token-audience", "", "Resource for which to request access tokens (default the cloud's resource manager endpoint)")
 
 	// resource info
 	rootCmd.PersistentFlags().StringP("resource-group", "g", "", "Name of the resource group")
 	rootCmd.PersistentFlags().StringP("zone", "z", "", "Name of the DNS zone")
+	rootCmd.PersistentFlags().Duration("zone-cache-ttl", time.Hour, "How long to cache the zones found when --zone is not given (0 disables caching)")
+	rootCmd.PersistentFlags().String("zone-cache", "", "Path to the zone cache file (default $HOME/.cache/az-dns/zones.json)")
+
+	// record sets
+	rootCmd.PersistentFlags().Int64("min-ttl", 0, "Smallest TTL that record sets may be given (0 for no limit)")
+	rootCmd.PersistentFlags().Int64("max-ttl", 0, "Largest TTL that record sets may be given (0 for no limit)")
+
+	// retries
+	rootCmd.PersistentFlags().Int("max-retries", helpers.DefaultMaxRetries, "Number of times to retry an Azure request that fails transiently")
+	rootCmd.PersistentFlags().Duration("retry-deadline", helpers.DefaultRetryDeadline, "Maximum time to spend on an Azure request, including retries (0 for no limit)")
 
 	// other
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Show the changes that would be made to record sets without making them")
+	rootCmd.PersistentFlags().Duration("timeout", 0, "Maximum time for the whole command (0 for no limit)")
+	rootCmd.PersistentFlags().StringP("output", "o", outputText, "Output format (text, json, yaml, table, or zone)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output")
 	if err := viper.BindPFlags(rootCmd.PersistentFlags()); err != nil {
 		// This shouldn't happen
@@ -86,4 +238,138 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil && viper.GetBool("verbose") {
 		fmt.Println("using config file:", viper.ConfigFileUsed())
 	}
+
+	configError = loadProfile()
+}
+
+// getZoneInfo returns the names of the resource group and DNS zone on which
+// commands should operate. If no zone has been provided, the zone containing
+// hostname is found among those in the subscription, which is impossible if
+// hostname is relative, and its defaults in the config file are applied. If no
+// resource group has been provided, it is taken from the zone's resource ID.
+// If either cannot be determined, an error will be returned.
+func getZoneInfo(ctx context.Context, hostname string, relative bool) (resourceGroup string, zone string, err error) {
+	var locator zoneLocator
+	resourceGroup, zone, discovered, err := locator.locate(ctx, hostname, relative)
+	if err != nil || !discovered {
+		return resourceGroup, zone, err
+	}
+
+	return resourceGroup, zone, applyZoneDefaults(zone)
+}
+
+// zoneLocator finds the resource group and DNS zone for hostnames as
+// getZoneInfo does, but leaves the zones' defaults for the caller to apply. A
+// single ZonesClient is created the first time a zone must be discovered, so
+// that a zoneLocator can be used for many hostnames.
+type zoneLocator struct {
+	client *dns.ZonesClient
+}
+
+// locate returns the resource group and DNS zone for hostname, and whether the
+// zone was discovered rather than provided.
+func (l *zoneLocator) locate(ctx context.Context, hostname string, relative bool) (resourceGroup string, zone string,
+	discovered bool, err error) {
+	resourceGroup = viper.GetString("resource-group")
+	zone = viper.GetString("zone")
+	if resourceGroup != "" && zone != "" {
+		return resourceGroup, zone, false, nil
+	}
+
+	target := zone
+	if target == "" {
+		hostname = strings.TrimRight(hostname, ".")
+		if hostname == "" || hostname == "@" || relative {
+			return "", "", false, &validationError{fmt.Errorf("a DNS zone name is required")}
+		}
+		target = hostname
+	}
+
+	if l.client == nil {
+		if l.client, err = newZonesClient(); err != nil {
+			return "", "", false, err
+		}
+	}
+
+	location, err := helpers.DiscoverZone(ctx, l.client, resourceGroup, target, getZoneCache())
+	if _, ok := err.(*helpers.ZoneNotFoundError); ok && zone != "" {
+		return "", "", false, &helpers.ZoneNotFoundError{Name: zone}
+	} else if err != nil {
+		return "", "", false, err
+	}
+
+	if zone != "" && !strings.EqualFold(location.Name, strings.TrimRight(zone, ".")) {
+		return "", "", false, &helpers.ZoneNotFoundError{Name: zone}
+	}
+
+	if location.ResourceGroup == "" {
+		return "", "", false, &validationError{fmt.Errorf("a resource group name is required")}
+	}
+
+	if viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "using zone %v in resource group %v\n", location.Name, location.ResourceGroup)
+	}
+
+	return location.ResourceGroup, location.Name, zone == "", nil
+}
+
+// getZoneCache returns the cache of zones used by getZoneInfo, which is
+// stored in the file given by --zone-cache, or under $XDG_CACHE_HOME or
+// $HOME/.cache by default.
+func getZoneCache() helpers.ZoneCache {
+	cache := helpers.ZoneCache{
+		Path:   viper.GetString("zone-cache"),
+		MaxAge: viper.GetDuration("zone-cache-ttl"),
+	}
+
+	if cache.Path == "" {
+		dir := os.Getenv("XDG_CACHE_HOME")
+		if dir == "" {
+			home, err := homedir.Dir()
+			if err != nil {
+				cache.MaxAge = 0
+				return cache
+			}
+			dir = filepath.Join(home, ".cache")
+		}
+		cache.Path = filepath.Join(dir, "az-dns", "zones.json")
+	}
+
+	return cache
+}
+
+// newRecordSetClient returns a RecordSetsClient for the cloud selected with
+// --cloud.
+func newRecordSetClient() (*dns.RecordSetsClient, error) {
+	cloud, err := helpers.GetCloud()
+	if err != nil {
+		return nil, &validationError{err}
+	}
+
+	return helpers.NewRecordSetClient(cloud)
+}
+
+// newZonesClient returns a ZonesClient for the cloud selected with --cloud.
+func newZonesClient() (*dns.ZonesClient, error) {
+	cloud, err := helpers.GetCloud()
+	if err != nil {
+		return nil, &validationError{err}
+	}
+
+	return helpers.NewZonesClient(cloud)
+}
+
+// getConditions returns the If-Match and If-None-Match values to send with a
+// change, as requested with the --if-match and --if-none-match flags. At most
+// one of them may be set.
+func getConditions() (ifMatch string, ifNoneMatch string, err error) {
+	ifMatch = viper.GetString("if-match")
+	if viper.GetBool("if-none-match") {
+		if ifMatch != "" {
+			return "", "", &validationError{fmt.Errorf("--if-match and --if-none-match cannot be combined")}
+		}
+		ifNoneMatch = "*"
+	}
+
+	return ifMatch, ifNoneMatch, nil
 }
@@ -3,12 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"net"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
 	"github.com/elyscape/az-dns/helpers"
-	"github.com/spf13/cast"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -21,11 +22,53 @@ var setCmd = &cobra.Command{
 
 This will create or update a record set on Azure DNS, depending on whether a
 record of the same type already exists for the provided value of HOSTNAME. The
-currently-supported record types are A, AAAA, CAA, and TXT. HOSTNAME may be a
-fully-qualified domain name contained within the zone, a record name relative
-to the zone, or either the empty string or @ for the apex. If a record name
-contains the zone name (e.g. example.com.example.com), you should either
-provide the FQDN or use the --relative flag.
+currently-supported record types are A, AAAA, CAA, CNAME, MX, NS, PTR, SRV, and
+TXT. HOSTNAME may be a fully-qualified domain name contained within the zone, a
+record name relative to the zone, or either the empty string or @ for the apex.
+If a record name contains the zone name (e.g. example.com.example.com), you
+should either provide the FQDN or use the --relative flag.
+
+Records made up of multiple fields are provided as consecutive values:
+    CAA    FLAGS TAG VALUE
+    MX     PREFERENCE EXCHANGE
+    SRV    PRIORITY WEIGHT PORT TARGET
+A CNAME record set must contain exactly one value.
+
+Each TXT value becomes one TXT record. Values longer than 255 bytes, such as
+DKIM keys, are split into several strings within the record, which resolvers
+join back together. With --quoted, each value is instead written as one or more
+quoted strings, as in a zone file, to choose how a record is split.
+
+Values are checked before anything is sent to Azure DNS: A records must be IPv4
+addresses and AAAA records IPv6 addresses; CNAME, NS, and PTR targets must be
+valid domain names and MX and SRV targets valid host names; and CAA tags and
+the values of issue, issuewild, and iodef properties must follow RFC 8659. A
+CNAME record set cannot be created at the apex or where record sets of other
+types exist, nor can other record sets be created where a CNAME record set
+exists.
+
+A new record set is given the TTL from --ttl. When a record set is replaced, it
+keeps its TTL unless --ttl is given explicitly. A new TTL must be within the
+limits set for the zone with min-ttl and max-ttl.
+
+The record set's metadata is kept when it is replaced. Metadata entries given
+with --meta KEY=VALUE are added to it, replacing any with the same key; see
+"az-dns meta --help" to remove entries.
+
+By default, the record set is replaced regardless of any changes made to it
+since it was last read. To guard against concurrent modification, pass
+--if-match with an etag from "az-dns get -o json" to replace the record set
+only if it is unchanged, or --if-none-match to create it only if it does not
+already exist. Alternatively, --optimistic reads the record set's current etag
+and writes conditionally on it, retrying with backoff if another client changes
+the record set in between. If a condition fails, or --optimistic runs out of
+retries, az-dns exits with status 5.
+
+With --dry-run, set prints the changes it would make as a diff instead of
+making them; --if-match and --if-none-match are not checked.
+
+With --wait, set does not return until the zone's name servers serve the new
+records; see "az-dns wait --help" for details.
 
 Examples:
     az-dns set A example.com 1.1.1.1 -z example.com
@@ -41,69 +84,121 @@ Examples:
     az-dns set CAA @ 0 issue letsencrypt.org 0 issuewild ';' -z example.com
         Creates CAA records at the apex of example.com with values:
             0 issue "letsencrypt.org"
-            0 issuewild ";"`,
+            0 issuewild ";"
+    az-dns set CNAME www example.com -z example.com
+        Creates a CNAME record for www.example.com pointing to example.com
+    az-dns set A www 1.1.1.1 --meta owner=web --meta ticket=OPS-123 -z example.com
+        Points www.example.com to 1.1.1.1 and tags it with an owner and ticket
+    az-dns set MX @ 10 mail1.example.com 20 mail2.example.com -z example.com
+        Creates MX records at the apex of example.com with values:
+            10 mail1.example.com
+            20 mail2.example.com
+    az-dns set SRV _sip._tcp 10 60 5060 sip.example.com -z example.com
+        Creates an SRV record for _sip._tcp.example.com with value:
+            10 60 5060 sip.example.com
+    az-dns set TXT @ --quoted '"v=spf1 include:a.example.net " "-all"' -z example.com
+        Creates a TXT record at the apex of example.com made up of two
+        strings, "v=spf1 include:a.example.net " and "-all"`,
 	Args: cobra.MinimumNArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		recordType := dns.RecordType(strings.ToUpper(args[0]))
 		hostname := args[1]
 		records := args[2:]
 
-		client, err := helpers.NewRecordSetClient(dns.DefaultBaseURI)
+		client, err := newRecordSetClient()
 		if err != nil {
 			return err
 		}
 
-		resourceGroup := viper.GetString("resource-group")
-		if resourceGroup == "" {
-			return fmt.Errorf("a resource group name is required")
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
 		}
 
-		zone := viper.GetString("zone")
-		if zone == "" {
-			return fmt.Errorf("a DNS zone name is required")
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
 		}
 
-		relative := viper.GetBool("relative")
 		ttl := viper.GetInt64("ttl")
 		recordName := helpers.GenerateRecordName(hostname, zone, relative)
+		if err := checkRecordName(recordName, recordType); err != nil {
+			return &validationError{err}
+		}
 
-		var rrparams *dns.RecordSet = nil
-		switch recordType {
-		case dns.A:
-			rrparams, err = generateARecordParams(ttl, records)
-			if err != nil {
-				return err
-			}
-		case dns.AAAA:
-			rrparams, err = generateAaaaRecordParams(ttl, records)
-			if err != nil {
-				return err
-			}
-		case dns.CAA:
-			rrparams, err = generateCaaRecordParams(ttl, records)
-			if err != nil {
-				return err
-			}
-		case dns.TXT:
-			rrparams, err = generateTxtRecordParams(ttl, records)
-			if err != nil {
-				return err
-			}
-		default:
-			return fmt.Errorf("unsupported record type %v", recordType)
+		rrparams, err := generateArgsRecordParams(recordType, ttl, records)
+		if err != nil {
+			return &validationError{err}
 		}
 
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		ifMatch, ifNoneMatch, err := getConditions()
+		if err != nil {
+			return err
+		}
+
+		metadata, err := helpers.ParseMetadata(viper.GetStringSlice("meta"))
+		if err != nil {
+			return &validationError{err}
+		}
+
+		optimistic := viper.GetBool("optimistic")
+		if optimistic && (ifMatch != "" || ifNoneMatch != "") {
+			return &validationError{fmt.Errorf("--optimistic cannot be combined with --if-match or --if-none-match")}
+		}
+
+		cmd.SilenceUsage = true
+
+		if err := checkCNAMEConflicts(ctx, client, resourceGroup, zone, recordName, recordType); err != nil {
+			return err
+		}
+
+		var newTTL *int64
+		if cmd.Flags().Changed("ttl") {
+			newTTL = &ttl
+		}
 
-		_, err = client.CreateOrUpdate(ctx, resourceGroup, zone, recordName, recordType, *rrparams, "", "")
+		replace := limitTTL(helpers.GetTTLLimits(), setRecordsModifier(rrparams, metadata, newTTL))
+		if isDryRun() {
+			return previewChange(ctx, os.Stdout, client, format, resourceGroup, zone, recordName, recordType, replace)
+		}
+
+		var rrset dns.RecordSet
+		if optimistic {
+			var result *dns.RecordSet
+			result, err = helpers.ModifyRecordSet(ctx, client, resourceGroup, zone, recordName, recordType,
+				viper.GetInt("conflict-retries"), replace)
+			if result != nil {
+				rrset = *result
+			}
+		} else {
+			// The record set is read only to keep its metadata; the write is
+			// subject to the conditions given, if any.
+			var desired *dns.RecordSet
+			_, desired, err = helpers.PreviewRecordSet(ctx, client, resourceGroup, zone, recordName, recordType, replace)
+			if err == nil {
+				rrset, err = client.CreateOrUpdate(ctx, resourceGroup, zone, recordName, recordType, *desired, ifMatch, ifNoneMatch)
+			}
+		}
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("success")
+		if viper.GetBool("wait") {
+			if err := waitForPropagation(ctx, cmd.Flags(), resourceGroup, zone, recordName, recordType, rrset.RecordSetProperties, true); err != nil {
+				return err
+			}
+		}
 
-		return nil
+		if format == outputText {
+			fmt.Println("success")
+			return nil
+		}
+
+		return printRecordSet(os.Stdout, format, rrset)
 	},
 }
 
@@ -111,19 +206,193 @@ func init() {
 	rootCmd.AddCommand(setCmd)
 
 	setCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	setCmd.PersistentFlags().Bool("quoted", false, "Each TXT value is one or more quoted strings, as in a zone file")
 	setCmd.PersistentFlags().Int64P("ttl", "t", 300, "Record set TTL")
+	setCmd.PersistentFlags().String("if-match", "", "Only replace the record set if its etag is ETAG")
+	setCmd.PersistentFlags().Bool("if-none-match", false, "Only create the record set if it does not exist")
+	setCmd.PersistentFlags().Bool("optimistic", false, "Read the record set's etag first and retry if it changes concurrently")
+	setCmd.PersistentFlags().StringSlice("meta", nil, "Metadata to add to the record set, as KEY=VALUE (may be repeated)")
+	setCmd.PersistentFlags().Int("conflict-retries", helpers.DefaultConflictRetries, "Number of times --optimistic retries after a conflict")
+	addWaitFlags(setCmd.PersistentFlags())
 	if err := viper.BindPFlags(setCmd.PersistentFlags()); err != nil {
 		// This shouldn't happen
 		panic(err)
 	}
 }
 
+// setRecordsModifier returns a modifier that replaces the records of a record
+// set with those of params. The record set's metadata is kept, except that the
+// entries in metadata are added to it or replace existing ones. An existing
+// record set's TTL is changed to ttl, or kept if ttl is nil; a new record set
+// takes the TTL of params.
+func setRecordsModifier(params *dns.RecordSet, metadata map[string]string, ttl *int64) helpers.RecordSetModifier {
+	return func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		var existing map[string]string
+		if current != nil && current.RecordSetProperties != nil && current.Metadata != nil {
+			existing = helpers.RecordSetMetadata(current)
+		}
+
+		props := *params.RecordSetProperties
+		props.Metadata = helpers.MetadataPointers(helpers.UpdateMetadata(existing, metadata, nil))
+		if current != nil && current.RecordSetProperties != nil {
+			props.TTL = current.TTL
+			if ttl != nil {
+				props.TTL = ttl
+			}
+		}
+
+		desired := *params
+		desired.RecordSetProperties = &props
+		return &desired, nil
+	}
+}
+
+// checkRecordName checks that a record set of type recordType may be created
+// at recordName, which must be a valid record name. A CNAME record set may not
+// be created at the apex.
+func checkRecordName(recordName string, recordType dns.RecordType) error {
+	if err := helpers.ValidateRecordName(recordName); err != nil {
+		return err
+	}
+
+	return helpers.ValidateCNAMEPlacement(recordName, recordType, nil)
+}
+
+// cnameConflictTypes are the record types that az-dns can create which may not
+// share a name with a CNAME record set.
+var cnameConflictTypes = []dns.RecordType{dns.A, dns.AAAA, dns.CAA, dns.MX, dns.NS, dns.PTR, dns.SRV, dns.TXT}
+
+// checkCNAMEConflicts checks that creating a record set of type recordType at
+// recordName would not leave a CNAME record set sharing its name with a record
+// set of another type. Rather than listing the zone, only the record sets that
+// could conflict are read: the CNAME record set, and if a CNAME record set is
+// being created, those of the other types. The check is skipped if the
+// credentials may not read them, as they may only be allowed to change the
+// record set being written.
+func checkCNAMEConflicts(ctx context.Context, client *dns.RecordSetsClient, resourceGroup string, zone string,
+	recordName string, recordType dns.RecordType) error {
+	if recordName == "@" {
+		// checkRecordName rejects CNAME record sets at the apex.
+		return nil
+	}
+
+	exists := func(existingType dns.RecordType) (bool, error) {
+		_, err := client.Get(ctx, resourceGroup, zone, recordName, existingType)
+		switch {
+		case err == nil:
+			return true, nil
+		case helpers.IsNotFound(err):
+			return false, nil
+		}
+		return false, err
+	}
+	skip := func(err error) error {
+		if info, ok := helpers.DescribeAzureError(err); ok && info.StatusCode == http.StatusForbidden {
+			if viper.GetBool("verbose") {
+				fmt.Fprintf(os.Stderr, "skipping CNAME conflict check for %v: %v\n", recordName, err)
+			}
+			return nil
+		}
+		return err
+	}
+
+	hasCNAME, err := exists(dns.CNAME)
+	if err != nil {
+		return skip(err)
+	}
+
+	existing := []dns.RecordType{}
+	switch {
+	case hasCNAME:
+		existing = append(existing, dns.CNAME)
+	case recordType == dns.CNAME:
+		for _, otherType := range cnameConflictTypes {
+			found, err := exists(otherType)
+			if err != nil {
+				return skip(err)
+			}
+			if found {
+				existing = append(existing, otherType)
+				break
+			}
+		}
+	}
+
+	if err := helpers.ValidateCNAMEPlacement(recordName, recordType, existing); err != nil {
+		return &validationError{err}
+	}
+
+	return nil
+}
+
+// generateArgsRecordParams creates the parameters for a record set from the
+// values given on the command line. With --quoted, each TXT value is parsed as
+// one or more quoted character strings, as in a zone file; otherwise values are
+// handled by generateRecordParams.
+func generateArgsRecordParams(recordType dns.RecordType, ttl int64, values []string) (*dns.RecordSet, error) {
+	if !viper.GetBool("quoted") {
+		return generateRecordParams(recordType, ttl, values)
+	}
+	if recordType != dns.TXT {
+		return nil, fmt.Errorf("--quoted can only be used with TXT records")
+	}
+
+	records := []dns.TxtRecord{}
+	for _, value := range values {
+		strs, err := helpers.ParseRecordFields(dns.TXT, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TXT record %q: %v", value, err)
+		}
+		if len(strs) == 0 {
+			return nil, fmt.Errorf("invalid TXT record %q must contain at least one string", value)
+		}
+
+		records = append(records, dns.TxtRecord{Value: splitTxtStrings(strs)})
+	}
+
+	rrparams := &dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:        &ttl,
+			TxtRecords: &records,
+		},
+	}
+
+	return rrparams, nil
+}
+
+// generateRecordParams creates the parameters for a record set of the given
+// type from values formatted as they would be on the command line.
+func generateRecordParams(recordType dns.RecordType, ttl int64, values []string) (*dns.RecordSet, error) {
+	switch recordType {
+	case dns.A:
+		return generateARecordParams(ttl, values)
+	case dns.AAAA:
+		return generateAaaaRecordParams(ttl, values)
+	case dns.CAA:
+		return generateCaaRecordParams(ttl, values)
+	case dns.CNAME:
+		return generateCnameRecordParams(ttl, values)
+	case dns.MX:
+		return generateMxRecordParams(ttl, values)
+	case dns.NS:
+		return generateNsRecordParams(ttl, values)
+	case dns.PTR:
+		return generatePtrRecordParams(ttl, values)
+	case dns.SRV:
+		return generateSrvRecordParams(ttl, values)
+	case dns.TXT:
+		return generateTxtRecordParams(ttl, values)
+	}
+
+	return nil, fmt.Errorf("unsupported record type %v", recordType)
+}
+
 func generateARecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
 	records := []dns.ARecord{}
 
 	for _, addr := range values {
-		if ip := net.ParseIP(addr); ip == nil || ip.To4() == nil {
-			return nil, fmt.Errorf(`invalid IP address "%v"`, addr)
+		if err := helpers.ValidateIPv4(addr); err != nil {
+			return nil, err
 		}
 		records = append(records, dns.ARecord{Ipv4Address: &addr})
 	}
@@ -142,8 +411,8 @@ func generateAaaaRecordParams(ttl int64, values []string) (*dns.RecordSet, error
 	records := []dns.AaaaRecord{}
 
 	for _, addr := range values {
-		if ip := net.ParseIP(addr); ip == nil || ip.To16() == nil {
-			return nil, fmt.Errorf(`invalid IP address "%v"`, addr)
+		if err := helpers.ValidateIPv6(addr); err != nil {
+			return nil, err
 		}
 		records = append(records, dns.AaaaRecord{Ipv6Address: &addr})
 	}
@@ -171,14 +440,17 @@ func generateCaaRecordParams(ttl int64, values []string) (*dns.RecordSet, error)
 
 		fields := values[min:max]
 
-		var flags int32
-		flags, err := cast.ToInt32E(fields[0])
-		if err != nil || flags > 255 || flags < 0 {
+		parsed, err := strconv.ParseUint(fields[0], 10, 8)
+		if err != nil {
 			return nil, fmt.Errorf(`invalid CAA flags "%v" must be an integer between 0 and 255`, fields[0])
 		}
+		flags := int32(parsed)
 
 		tag := fields[1]
 		value := fields[2]
+		if err := helpers.ValidateCAA(tag, value); err != nil {
+			return nil, err
+		}
 
 		records = append(records, dns.CaaRecord{
 			Flags: &flags,
@@ -197,12 +469,175 @@ func generateCaaRecordParams(ttl int64, values []string) (*dns.RecordSet, error)
 	return rrparams, nil
 }
 
+func generateCnameRecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
+	if len(values) != 1 {
+		return nil, fmt.Errorf("a CNAME record set must contain exactly one value, got %v", len(values))
+	}
+
+	cname := values[0]
+	if err := helpers.ValidateDomainName(cname); err != nil {
+		return nil, fmt.Errorf("invalid CNAME target: %v", err)
+	}
+
+	rrparams := &dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:         &ttl,
+			CnameRecord: &dns.CnameRecord{Cname: &cname},
+		},
+	}
+
+	return rrparams, nil
+}
+
+func generateMxRecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
+	records := []dns.MxRecord{}
+
+	const recordSize = 2
+
+	for min := 0; min < len(values); min += recordSize {
+		max := min + recordSize
+		if max > len(values) {
+			return nil, fmt.Errorf(`incomplete MX record %v`, values[min:])
+		}
+
+		fields := values[min:max]
+
+		preference, err := parseUint16Field("MX preference", fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		exchange := fields[1]
+		records = append(records, dns.MxRecord{
+			Preference: &preference,
+			Exchange:   &exchange,
+		})
+	}
+
+	if err := helpers.ValidateMXExchanges(records); err != nil {
+		return nil, err
+	}
+
+	rrparams := &dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:       &ttl,
+			MxRecords: &records,
+		},
+	}
+
+	return rrparams, nil
+}
+
+func generateNsRecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
+	records := []dns.NsRecord{}
+
+	for _, value := range values {
+		nsdname := value
+		if err := helpers.ValidateDomainName(nsdname); err != nil {
+			return nil, fmt.Errorf("invalid NS name server: %v", err)
+		}
+		records = append(records, dns.NsRecord{Nsdname: &nsdname})
+	}
+
+	rrparams := &dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:       &ttl,
+			NsRecords: &records,
+		},
+	}
+
+	return rrparams, nil
+}
+
+func generatePtrRecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
+	records := []dns.PtrRecord{}
+
+	for _, value := range values {
+		ptrdname := value
+		if err := helpers.ValidateDomainName(ptrdname); err != nil {
+			return nil, fmt.Errorf("invalid PTR domain name: %v", err)
+		}
+		records = append(records, dns.PtrRecord{Ptrdname: &ptrdname})
+	}
+
+	rrparams := &dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:        &ttl,
+			PtrRecords: &records,
+		},
+	}
+
+	return rrparams, nil
+}
+
+func generateSrvRecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
+	records := []dns.SrvRecord{}
+
+	const recordSize = 4
+
+	for min := 0; min < len(values); min += recordSize {
+		max := min + recordSize
+		if max > len(values) {
+			return nil, fmt.Errorf(`incomplete SRV record %v`, values[min:])
+		}
+
+		fields := values[min:max]
+
+		priority, err := parseUint16Field("SRV priority", fields[0])
+		if err != nil {
+			return nil, err
+		}
+
+		weight, err := parseUint16Field("SRV weight", fields[1])
+		if err != nil {
+			return nil, err
+		}
+
+		port, err := parseUint16Field("SRV port", fields[2])
+		if err != nil {
+			return nil, err
+		}
+
+		target := fields[3]
+		records = append(records, dns.SrvRecord{
+			Priority: &priority,
+			Weight:   &weight,
+			Port:     &port,
+			Target:   &target,
+		})
+	}
+
+	if err := helpers.ValidateSRVTargets(records); err != nil {
+		return nil, err
+	}
+
+	rrparams := &dns.RecordSet{
+		RecordSetProperties: &dns.RecordSetProperties{
+			TTL:        &ttl,
+			SrvRecords: &records,
+		},
+	}
+
+	return rrparams, nil
+}
+
+// parseUint16Field parses value as a decimal unsigned 16-bit integer, as used
+// by the numeric fields of MX and SRV records. The returned error refers to the
+// field by name.
+func parseUint16Field(name string, value string) (int32, error) {
+	parsed, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid %v "%v" must be an integer between 0 and 65535`, name, value)
+	}
+
+	return int32(parsed), nil
+}
+
 func generateTxtRecordParams(ttl int64, values []string) (*dns.RecordSet, error) {
 	records := []dns.TxtRecord{}
 
 	for _, value := range values {
-		valueArr := []string{value}
-		records = append(records, dns.TxtRecord{Value: &valueArr})
+		records = append(records, dns.TxtRecord{Value: splitTxtStrings([]string{value})})
 	}
 
 	rrparams := &dns.RecordSet{
@@ -214,3 +649,15 @@ func generateTxtRecordParams(ttl int64, values []string) (*dns.RecordSet, error)
 
 	return rrparams, nil
 }
+
+// splitTxtStrings splits any of strs that are longer than a character string
+// may be into several, so that long values such as DKIM keys can be stored in
+// a single TXT record. Resolvers join them back together.
+func splitTxtStrings(strs []string) *[]string {
+	result := []string{}
+	for _, str := range strs {
+		result = append(result, helpers.SplitCharacterString(str)...)
+	}
+
+	return &result
+}
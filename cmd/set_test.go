@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+type uint16FieldTestCase struct {
+	value    string
+	expected int32
+	valid    bool
+}
+
+var uint16FieldTests = []uint16FieldTestCase{
+	{"0", 0, true},
+	{"10", 10, true},
+	{"010", 10, true},
+	{"65535", 65535, true},
+	{"65536", 0, false},
+	{"-1", 0, false},
+	{"0x10", 0, false},
+	{"1e3", 0, false},
+	{"", 0, false},
+}
+
+func TestParseUint16Field(t *testing.T) {
+	for _, testCase := range uint16FieldTests {
+		t.Run(testCase.value, func(t *testing.T) { testParseUint16Field(t, testCase) })
+	}
+}
+
+func testParseUint16Field(t *testing.T, testCase uint16FieldTestCase) {
+	result, err := parseUint16Field("MX preference", testCase.value)
+	if !testCase.valid {
+		assert.Error(t, err)
+		return
+	}
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, testCase.expected, result)
+	}
+}
+
+type caaFlagsTestCase struct {
+	flags    string
+	expected int32
+	valid    bool
+}
+
+var caaFlagsTests = []caaFlagsTestCase{
+	{"0", 0, true},
+	{"128", 128, true},
+	{"0128", 128, true},
+	{"255", 255, true},
+	{"256", 0, false},
+	{"0x80", 0, false},
+}
+
+func TestGenerateCaaRecordParams(t *testing.T) {
+	for _, testCase := range caaFlagsTests {
+		t.Run(testCase.flags, func(t *testing.T) { testGenerateCaaRecordParams(t, testCase) })
+	}
+}
+
+func testGenerateCaaRecordParams(t *testing.T, testCase caaFlagsTestCase) {
+	result, err := generateCaaRecordParams(300, []string{testCase.flags, "issue", "letsencrypt.org"})
+	if !testCase.valid {
+		assert.Error(t, err)
+		return
+	}
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, testCase.expected, *(*result.CaaRecords)[0].Flags)
+	}
+}
+
+type setRecordsModifierTestCase struct {
+	name        string
+	current     *dns.RecordSet
+	ttl         *int64
+	expectedTTL int64
+}
+
+var setRecordsModifierTests = []setRecordsModifierTestCase{
+	{"new", nil, nil, 300},
+	{"existing", recordSetWithTTL(3600), nil, 3600},
+	{"existing with TTL", recordSetWithTTL(3600), to.Int64Ptr(60), 60},
+	{"existing with same TTL", recordSetWithTTL(300), to.Int64Ptr(300), 300},
+	{"existing without properties", &dns.RecordSet{}, nil, 300},
+}
+
+func TestSetRecordsModifier(t *testing.T) {
+	for _, testCase := range setRecordsModifierTests {
+		t.Run(testCase.name, func(t *testing.T) { testSetRecordsModifier(t, testCase) })
+	}
+}
+
+func testSetRecordsModifier(t *testing.T, testCase setRecordsModifierTestCase) {
+	params, err := generateARecordParams(300, []string{"192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desired, err := setRecordsModifier(params, nil, testCase.ttl)(testCase.current)
+	if assert.NoError(t, err) {
+		assert.Equal(t, testCase.expectedTTL, to.Int64(desired.TTL))
+		assert.Equal(t, params.ARecords, desired.ARecords)
+	}
+}
+
+func TestSetRecordsModifierKeepsTTLOutsideLimits(t *testing.T) {
+	params, err := generateARecordParams(300, []string{"192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := helpers.TTLLimits{Min: 600}
+
+	// A record set's TTL that is kept is not checked against the limits.
+	desired, err := limitTTL(limits, setRecordsModifier(params, nil, nil))(recordSetWithTTL(60))
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(60), to.Int64(desired.TTL))
+	}
+
+	_, err = limitTTL(limits, setRecordsModifier(params, nil, to.Int64Ptr(120)))(recordSetWithTTL(60))
+	assert.IsType(t, &helpers.TTLLimitError{}, err)
+}
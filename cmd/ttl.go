@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ttlCmd represents the ttl command
+var ttlCmd = &cobra.Command{
+	Use:   "ttl TYPE HOSTNAME TTL",
+	Short: "Change the TTL of a DNS record set",
+	Long: `Change the TTL of a record set in Azure DNS
+
+This will change the TTL of an existing record set to TTL seconds, leaving its
+records and metadata alone. HOSTNAME is given exactly as it is for set; see
+"az-dns set --help" for details. Only the TTL is sent to Azure DNS, so records
+added by another client in the meantime are not lost. To change the TTL only if
+the record set is unchanged since it was read, pass --if-match with its etag.
+
+The TTL must be within the limits set for the zone with min-ttl and max-ttl;
+see "az-dns --help" for details.
+
+Examples:
+    az-dns ttl A www 3600 -z example.com
+        Sets the TTL of the A record set for www.example.com to an hour
+    az-dns ttl TXT _acme-challenge 60 -z example.com
+        Sets the TTL of the TXT record set for _acme-challenge.example.com to a
+        minute`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recordType := dns.RecordType(strings.ToUpper(args[0]))
+		hostname := args[1]
+
+		ttl, err := cast.ToInt64E(args[2])
+		if err != nil || ttl < 0 {
+			return &validationError{fmt.Errorf(`invalid TTL "%v" must be a non-negative integer`, args[2])}
+		}
+
+		client, err := newRecordSetClient()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		ifMatch, _, err := getConditions()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		if err := helpers.GetTTLLimits().Check(ttl); err != nil {
+			return err
+		}
+
+		recordName := helpers.GenerateRecordName(hostname, zone, relative)
+
+		if isDryRun() {
+			return previewChange(ctx, os.Stdout, client, format, resourceGroup, zone, recordName, recordType,
+				func(current *dns.RecordSet) (*dns.RecordSet, error) {
+					if current == nil || current.RecordSetProperties == nil {
+						return nil, &helpers.RecordSetNotFoundError{Name: recordName, Type: recordType}
+					}
+					props := *current.RecordSetProperties
+					props.TTL = &ttl
+					return &dns.RecordSet{Type: current.Type, RecordSetProperties: &props}, nil
+				})
+		}
+
+		params := dns.RecordSet{
+			RecordSetProperties: &dns.RecordSetProperties{TTL: &ttl},
+		}
+		rrset, err := client.Update(ctx, resourceGroup, zone, recordName, recordType, params, ifMatch)
+		if err != nil {
+			return err
+		}
+
+		if format == outputText {
+			fmt.Println("success")
+			return nil
+		}
+
+		return printRecordSet(os.Stdout, format, rrset)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(ttlCmd)
+
+	ttlCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	ttlCmd.PersistentFlags().String("if-match", "", "Only change the record set if its etag is ETAG")
+	if err := viper.BindPFlags(ttlCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// zoneTTLLimits returns the TTL limits for zone, taking its defaults in the
+// config file into account without applying them. flags are those of the
+// command being run.
+func zoneTTLLimits(flags *pflag.FlagSet, zone string) helpers.TTLLimits {
+	return helpers.TTLLimits{
+		Min: cast.ToInt64(zoneSetting(flags, zone, "min-ttl")),
+		Max: cast.ToInt64(zoneSetting(flags, zone, "max-ttl")),
+	}
+}
+
+// limitTTL wraps modify so that it fails with a *helpers.TTLLimitError if it
+// would create a record set, or change the TTL of one, with a TTL outside
+// limits. Record sets whose TTL is unchanged are not checked, so that ones
+// created before the limits were set can still be changed.
+func limitTTL(limits helpers.TTLLimits, modify helpers.RecordSetModifier) helpers.RecordSetModifier {
+	return func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		desired, err := modify(current)
+		if err != nil || desired == nil || desired == current || desired.RecordSetProperties == nil {
+			return desired, err
+		}
+
+		ttl := to.Int64(desired.TTL)
+		if current != nil && current.RecordSetProperties != nil && to.Int64(current.TTL) == ttl {
+			return desired, nil
+		}
+
+		return desired, limits.Check(ttl)
+	}
+}
+
+// checkChangeTTLs checks the TTLs of the record sets that changes would create
+// or update against limits, in the same manner as limitTTL.
+func checkChangeTTLs(limits helpers.TTLLimits, changes []helpers.RecordSetChange) error {
+	for _, change := range changes {
+		if change.Desired == nil || change.Desired.RecordSetProperties == nil {
+			continue
+		}
+
+		ttl := to.Int64(change.Desired.TTL)
+		if change.Current != nil && change.Current.RecordSetProperties != nil && to.Int64(change.Current.TTL) == ttl {
+			continue
+		}
+
+		if err := limits.Check(ttl); err != nil {
+			return &validationError{fmt.Errorf("%v record set %v: %v", change.Type, change.Name, err)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/stretchr/testify/assert"
+)
+
+func recordSetWithTTL(ttl int64) *dns.RecordSet {
+	return &dns.RecordSet{RecordSetProperties: &dns.RecordSetProperties{TTL: to.Int64Ptr(ttl)}}
+}
+
+type ttlLimitTestCase struct {
+	name    string
+	limits  helpers.TTLLimits
+	current *dns.RecordSet
+	desired *dns.RecordSet
+	valid   bool
+}
+
+var ttlLimits = helpers.TTLLimits{Min: 300, Max: 86400}
+
+var ttlLimitTests = []ttlLimitTestCase{
+	// New record sets
+	{"new", ttlLimits, nil, recordSetWithTTL(3600), true},
+	{"new at minimum", ttlLimits, nil, recordSetWithTTL(300), true},
+	{"new at maximum", ttlLimits, nil, recordSetWithTTL(86400), true},
+	{"new below minimum", ttlLimits, nil, recordSetWithTTL(60), false},
+	{"new above maximum", ttlLimits, nil, recordSetWithTTL(172800), false},
+
+	// Changed record sets
+	{"changed", ttlLimits, recordSetWithTTL(3600), recordSetWithTTL(600), true},
+	{"changed below minimum", ttlLimits, recordSetWithTTL(3600), recordSetWithTTL(60), false},
+	{"changed above maximum", ttlLimits, recordSetWithTTL(3600), recordSetWithTTL(172800), false},
+	{"unchanged below minimum", ttlLimits, recordSetWithTTL(60), recordSetWithTTL(60), true},
+	{"unchanged above maximum", ttlLimits, recordSetWithTTL(172800), recordSetWithTTL(172800), true},
+
+	// Other cases
+	{"deleted", ttlLimits, recordSetWithTTL(60), nil, true},
+	{"minimum only", helpers.TTLLimits{Min: 300}, nil, recordSetWithTTL(604800), true},
+	{"maximum only", helpers.TTLLimits{Max: 3600}, nil, recordSetWithTTL(1), true},
+	{"no limits", helpers.TTLLimits{}, nil, recordSetWithTTL(0), true},
+}
+
+func TestLimitTTL(t *testing.T) {
+	for _, testCase := range ttlLimitTests {
+		t.Run(testCase.name, func(t *testing.T) { testLimitTTL(t, testCase) })
+	}
+}
+
+func testLimitTTL(t *testing.T, testCase ttlLimitTestCase) {
+	modify := func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		assert.Equal(t, testCase.current, current)
+		return testCase.desired, nil
+	}
+
+	desired, err := limitTTL(testCase.limits, modify)(testCase.current)
+	assert.Equal(t, testCase.desired, desired)
+	if testCase.valid {
+		assert.NoError(t, err)
+	} else {
+		assert.IsType(t, &helpers.TTLLimitError{}, err)
+	}
+}
+
+func TestCheckChangeTTLs(t *testing.T) {
+	for _, testCase := range ttlLimitTests {
+		t.Run(testCase.name, func(t *testing.T) { testCheckChangeTTLs(t, testCase) })
+	}
+}
+
+func testCheckChangeTTLs(t *testing.T, testCase ttlLimitTestCase) {
+	changes := []helpers.RecordSetChange{
+		{Name: "ok", Type: dns.A, Desired: recordSetWithTTL(3600)},
+		{Name: "www", Type: dns.A, Current: testCase.current, Desired: testCase.desired},
+	}
+
+	err := checkChangeTTLs(testCase.limits, changes)
+	if testCase.valid {
+		assert.NoError(t, err)
+	} else {
+		assert.True(t, isValidationError(err))
+		assert.Contains(t, err.Error(), "A record set www")
+	}
+}
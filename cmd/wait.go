@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// waitCmd represents the wait command
+var waitCmd = &cobra.Command{
+	Use:   "wait TYPE HOSTNAME [VALUES]",
+	Short: "Wait for DNS records to be served",
+	Long: `Wait until the name servers for a zone serve the given records
+
+This will query each of the zone's authoritative name servers directly, without
+going through any caching resolvers, until every one of them serves all of the
+records given by VALUES at HOSTNAME, or until --wait-timeout has passed. With
+--absent, it instead waits until none of them serve any of those records, or
+any records of type TYPE at all if no VALUES are given. HOSTNAME and VALUES are
+given exactly as they are for set; see "az-dns set --help" for details.
+
+The name servers are taken from the zone in Azure DNS unless --name-servers is
+given. Queries are sent over UDP, falling back to TCP if a response does not
+fit in a single datagram.
+
+The set, add, remove, and clear commands, as well as acme, accept a --wait flag
+that does the same once their change has been made.
+
+Examples:
+    az-dns wait TXT _acme-challenge token -z example.com
+        Waits for "token" to be served in the TXT records for
+        _acme-challenge.example.com
+    az-dns wait CNAME www --absent -z example.com
+        Waits for the CNAME record for www.example.com to disappear`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recordType := dns.RecordType(strings.ToUpper(args[0]))
+		hostname := args[1]
+		records := args[2:]
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		relative := viper.GetBool("relative")
+		resourceGroup, zone, err := getZoneInfo(ctx, hostname, relative)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		absent := viper.GetBool("absent")
+		if len(records) == 0 && !absent {
+			return &validationError{fmt.Errorf("at least one value is required unless --absent is given")}
+		}
+
+		var props *dns.RecordSetProperties
+		if len(records) > 0 {
+			rrparams, err := generateArgsRecordParams(recordType, 0, records)
+			if err != nil {
+				return &validationError{err}
+			}
+			props = rrparams.RecordSetProperties
+		}
+
+		recordName := helpers.GenerateRecordName(hostname, zone, relative)
+
+		cmd.SilenceUsage = true
+
+		if err := waitForPropagation(ctx, cmd.Flags(), resourceGroup, zone, recordName, recordType, props, !absent); err != nil {
+			return err
+		}
+
+		return printResult(os.Stdout, format, resultOutput{
+			Name:   recordName,
+			Zone:   zone,
+			Type:   string(recordType),
+			Result: "served",
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(waitCmd)
+
+	waitCmd.PersistentFlags().BoolP("relative", "r", false, "HOSTNAME is a zone-relative label")
+	waitCmd.PersistentFlags().Bool("quoted", false, "Each TXT value is one or more quoted strings, as in a zone file")
+	waitCmd.PersistentFlags().Bool("absent", false, "Wait for the records to stop being served")
+	addWaitTimingFlags(waitCmd.PersistentFlags())
+	if err := viper.BindPFlags(waitCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// addWaitFlags adds the flags used by commands that can wait for their changes
+// to be served by a zone's name servers.
+func addWaitFlags(flags *pflag.FlagSet) {
+	flags.Bool("wait", false, "Wait for the change to be served by the zone's name servers")
+	addWaitTimingFlags(flags)
+}
+
+// addWaitTimingFlags adds the flags that control how waitForPropagation
+// queries name servers.
+func addWaitTimingFlags(flags *pflag.FlagSet) {
+	flags.Duration("wait-timeout", 2*time.Minute, "Maximum time to wait for name servers to serve a change")
+	flags.Duration("wait-interval", 5*time.Second, "Time between queries to name servers that have not yet served a change")
+	flags.StringSlice("name-servers", nil, "Name servers to query instead of those of the zone, as HOST[:PORT]")
+}
+
+// waitForPropagation waits until every name server for the zone serves the
+// records in props at recordName if present is true, or none of them if it is
+// false. If props is nil and present is false, it waits for the record set to
+// disappear entirely. The wait settings are those for the zone, given flags,
+// the flags of the command being run.
+func waitForPropagation(ctx context.Context, flags *pflag.FlagSet, resourceGroup string, zone string, recordName string,
+	recordType dns.RecordType, props *dns.RecordSetProperties, present bool) error {
+	servers, err := getNameServers(ctx, cast.ToStringSlice(zoneSetting(flags, zone, "name-servers")), resourceGroup, zone)
+	if err != nil {
+		return err
+	}
+
+	fqdn := zone
+	if recordName != "@" {
+		fqdn = recordName + "." + zone
+	}
+
+	if viper.GetBool("verbose") {
+		fmt.Fprintf(os.Stderr, "waiting for %v records at %v on %v\n", recordType, fqdn, strings.Join(servers, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cast.ToDuration(zoneSetting(flags, zone, "wait-timeout")))
+	defer cancel()
+
+	expected := helpers.ExpectedAnswers(recordType, props)
+	interval := cast.ToDuration(zoneSetting(flags, zone, "wait-interval"))
+	return helpers.WaitForRecords(ctx, servers, fqdn, recordType, expected, present, interval)
+}
+
+// getNameServers returns the addresses of the name servers that should be
+// queried for records in the zone: names, as given with --name-servers, or if
+// there are none, the zone's name servers in Azure DNS.
+func getNameServers(ctx context.Context, names []string, resourceGroup string, zone string) ([]string, error) {
+	if len(names) == 0 {
+		client, err := newZonesClient()
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := client.Get(ctx, resourceGroup, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.ZoneProperties != nil {
+			names = to.StringSlice(result.NameServers)
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("the zone %v has no name servers", zone)
+		}
+	}
+
+	servers := []string{}
+	for _, name := range names {
+		if _, _, err := net.SplitHostPort(name); err != nil {
+			name = net.JoinHostPort(strings.TrimRight(name, "."), "53")
+		}
+		servers = append(servers, name)
+	}
+
+	return servers, nil
+}
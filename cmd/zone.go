@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// zoneCmd represents the zone command
+var zoneCmd = &cobra.Command{
+	Use:   "zone",
+	Short: "Manage DNS zones",
+	Long: `Create, delete, and inspect Azure DNS zones
+
+These commands operate on DNS zones as a whole rather than on the record sets
+within them. Where a command takes a ZONE argument, it may be omitted in favor
+of the --zone flag.`,
+}
+
+func init() {
+	rootCmd.AddCommand(zoneCmd)
+}
+
+// getZoneArg returns the names of the resource group and the DNS zone named on
+// the command line, falling back to the value of the --zone flag if no zone was
+// given. The zone's defaults from the config file are applied. If either name
+// is missing, an error will be returned.
+func getZoneArg(args []string) (resourceGroup string, zone string, err error) {
+	zone = viper.GetString("zone")
+	if len(args) > 0 {
+		zone = args[0]
+		if err := applyZoneDefaults(zone); err != nil {
+			return "", "", err
+		}
+	}
+
+	resourceGroup = viper.GetString("resource-group")
+	if resourceGroup == "" {
+		return "", "", &validationError{fmt.Errorf("a resource group name is required")}
+	}
+
+	zone = strings.TrimRight(zone, ".")
+	if zone == "" {
+		return "", "", &validationError{fmt.Errorf("a DNS zone name is required")}
+	}
+
+	return resourceGroup, zone, nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/spf13/cobra"
+)
+
+// zoneCreateCmd represents the zone create command
+var zoneCreateCmd = &cobra.Command{
+	Use:   "create [ZONE]",
+	Short: "Create a DNS zone",
+	Long: `Create a DNS zone in Azure DNS
+
+This will create a new, empty DNS zone in the given resource group and print
+its details, including the name servers to which the zone must be delegated.
+If the zone already exists, it is left untouched and an error is returned.
+
+Examples:
+    az-dns zone create sub.example.com -g dns
+        Creates the zone sub.example.com in the resource group dns`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newZonesClient()
+		if err != nil {
+			return err
+		}
+
+		resourceGroup, zone, err := getZoneArg(args)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		// Azure DNS zones are global resources.
+		params := dns.Zone{Location: to.StringPtr("global")}
+
+		result, err := client.CreateOrUpdate(ctx, resourceGroup, zone, params, "", "*")
+		if err != nil {
+			return err
+		}
+
+		return printZone(os.Stdout, format, result)
+	},
+}
+
+func init() {
+	zoneCmd.AddCommand(zoneCreateCmd)
+}
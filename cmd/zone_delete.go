@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// zoneDeletePollingDelay is how long to wait between checks on the progress
+// of a zone deletion when Azure does not suggest an interval.
+const zoneDeletePollingDelay = 5 * time.Second
+
+// zoneDeleteCmd represents the zone delete command
+var zoneDeleteCmd = &cobra.Command{
+	Use:   "delete [ZONE]",
+	Short: "Delete a DNS zone",
+	Long: `Delete a DNS zone from Azure DNS
+
+This will delete a DNS zone and every record set within it. This cannot be
+undone, so the --yes flag must be given to confirm the deletion. Zone deletion
+is a long-running operation; by default, the command waits for it to finish,
+printing progress to standard error. Use --no-wait to return as soon as Azure
+has accepted the request.
+
+Examples:
+    az-dns zone delete sub.example.com -g dns --yes
+        Deletes the zone sub.example.com and waits for the deletion to finish`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newZonesClient()
+		if err != nil {
+			return err
+		}
+
+		resourceGroup, zone, err := getZoneArg(args)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		if !viper.GetBool("yes") {
+			return &validationError{fmt.Errorf("deleting zone %v would delete all of its record sets; pass --yes to confirm", zone)}
+		}
+
+		cmd.SilenceUsage = true
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		future, err := client.Delete(ctx, resourceGroup, zone, "")
+		if err != nil {
+			return err
+		}
+
+		result := "deleted"
+		if viper.GetBool("no-wait") {
+			result = "deleting"
+		} else if err := waitForZoneDeletion(ctx, client, zone, &future); err != nil {
+			return err
+		}
+
+		return printResult(os.Stdout, format, resultOutput{
+			Name:   "@",
+			Zone:   zone,
+			Type:   "zone",
+			Result: result,
+		})
+	},
+}
+
+func init() {
+	zoneCmd.AddCommand(zoneDeleteCmd)
+
+	zoneDeleteCmd.PersistentFlags().Bool("yes", false, "Confirm the deletion of the zone and all of its record sets")
+	zoneDeleteCmd.PersistentFlags().Bool("no-wait", false, "Do not wait for the deletion to finish")
+	if err := viper.BindPFlags(zoneDeleteCmd.PersistentFlags()); err != nil {
+		// This shouldn't happen
+		panic(err)
+	}
+}
+
+// waitForZoneDeletion polls a zone deletion until it has finished, reporting
+// its status to standard error after each check.
+func waitForZoneDeletion(ctx context.Context, client *dns.ZonesClient, zone string, future *dns.ZonesDeleteFuture) error {
+	start := time.Now()
+
+	for {
+		done, err := future.Done(client)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+
+		delay, ok := future.GetPollingDelay()
+		if !ok {
+			delay = zoneDeletePollingDelay
+		}
+
+		fmt.Fprintf(os.Stderr, "waiting for deletion of %v: %v (%v elapsed)\n", zone, future.Status(),
+			time.Since(start).Round(time.Second))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	_, err := future.Result(*client)
+	return err
+}
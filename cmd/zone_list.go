@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/elyscape/az-dns/helpers"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// zoneListCmd represents the zone list command
+var zoneListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List DNS zones",
+	Long: `List DNS zones in Azure DNS
+
+This will print every DNS zone in the resource group given by --resource-group,
+or every DNS zone in the subscription if no resource group is provided.
+
+Examples:
+    az-dns zone list
+        Prints every DNS zone in the subscription
+    az-dns zone list -g dns
+        Prints every DNS zone in the resource group dns`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newZonesClient()
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		zones, err := helpers.ListZones(ctx, client, viper.GetString("resource-group"))
+		if err != nil {
+			return err
+		}
+
+		return printZones(os.Stdout, format, zones)
+	},
+}
+
+func init() {
+	zoneCmd.AddCommand(zoneListCmd)
+}
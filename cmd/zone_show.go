@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// zoneShowCmd represents the zone show command
+var zoneShowCmd = &cobra.Command{
+	Use:   "show [ZONE]",
+	Short: "Show the details of a DNS zone",
+	Long: `Show the details of a DNS zone in Azure DNS
+
+This will print the name servers of a DNS zone along with the number of record
+sets it contains and the maximum number it may contain.
+
+Examples:
+    az-dns zone show example.com -g dns
+        Prints the details of example.com
+    az-dns zone show -z example.com -g dns -o json
+        Prints the details of example.com as JSON`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newZonesClient()
+		if err != nil {
+			return err
+		}
+
+		resourceGroup, zone, err := getZoneArg(args)
+		if err != nil {
+			return err
+		}
+
+		format, err := getOutputFormat()
+		if err != nil {
+			return err
+		}
+
+		cmd.SilenceUsage = true
+
+		ctx, cancel := newCommandContext()
+		defer cancel()
+
+		result, err := client.Get(ctx, resourceGroup, zone)
+		if err != nil {
+			return err
+		}
+
+		return printZone(os.Stdout, format, result)
+	},
+}
+
+func init() {
+	zoneCmd.AddCommand(zoneShowCmd)
+}
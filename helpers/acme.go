@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// AcmeChallengeLabel is the label prepended to a domain name to form the name
+// of the TXT record used for an ACME DNS-01 challenge.
+const AcmeChallengeLabel = "_acme-challenge"
+
+// AcmeChallengeFqdn returns the fully-qualified name, without a trailing dot,
+// of the TXT record that proves control of domain. A wildcard domain is
+// validated using the same record as its base domain.
+func AcmeChallengeFqdn(domain string) string {
+	domain = strings.TrimRight(domain, ".")
+	domain = strings.TrimPrefix(domain, "*.")
+
+	return AcmeChallengeLabel + "." + domain
+}
+
+// AcmeChallengeValue returns the value of the TXT record for an ACME DNS-01
+// challenge with the given key authorization, as defined by RFC 8555 section
+// 8.4: the unpadded base64url encoding of its SHA-256 digest.
+func AcmeChallengeValue(keyAuthorization string) string {
+	digest := sha256.Sum256([]byte(keyAuthorization))
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// InZone reports whether the domain name fqdn is within zone, including at its
+// apex. Names are compared case-insensitively and trailing dots are ignored.
+func InZone(fqdn string, zone string) bool {
+	fqdn = strings.ToLower(strings.TrimRight(fqdn, "."))
+	zone = strings.ToLower(strings.TrimRight(zone, "."))
+
+	return fqdn == zone || strings.HasSuffix(fqdn, "."+zone)
+}
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type acmeChallengeFqdnTestCase struct {
+	domain       string
+	expectedFqdn string
+}
+
+var acmeChallengeFqdnTests = []acmeChallengeFqdnTestCase{
+	{"example.com", "_acme-challenge.example.com"},
+	{"example.com.", "_acme-challenge.example.com"},
+	{"www.example.com", "_acme-challenge.www.example.com"},
+	{"*.example.com", "_acme-challenge.example.com"},
+	{"*.sub.example.com.", "_acme-challenge.sub.example.com"},
+}
+
+func TestAcmeChallengeFqdn(t *testing.T) {
+	for _, testCase := range acmeChallengeFqdnTests {
+		t.Run(testCase.domain, func(t *testing.T) { testAcmeChallengeFqdn(t, testCase) })
+	}
+}
+
+func testAcmeChallengeFqdn(t *testing.T, testCase acmeChallengeFqdnTestCase) {
+	assert.Equal(t, testCase.expectedFqdn, AcmeChallengeFqdn(testCase.domain))
+}
+
+func TestAcmeChallengeValue(t *testing.T) {
+	assert.Equal(t, "61rBZ_4knHblO0MNoxFsXZ_eTFUHum0B6IVRbhvUn5I", AcmeChallengeValue("token.thumbprint"))
+}
+
+type inZoneTestCase struct {
+	fqdn     string
+	zone     string
+	expected bool
+}
+
+var inZoneTests = []inZoneTestCase{
+	{"example.com", "example.com", true},
+	{"example.com.", "example.com", true},
+	{"_acme-challenge.example.com", "example.com.", true},
+	{"WWW.Example.com", "example.COM", true},
+	{"myexample.com", "example.com", false},
+	{"example.org", "example.com", false},
+	{"com", "example.com", false},
+}
+
+func TestInZone(t *testing.T) {
+	for _, testCase := range inZoneTests {
+		name := fmt.Sprintf("%v in %v", testCase.fqdn, testCase.zone)
+		t.Run(name, func(t *testing.T) { testInZone(t, testCase) })
+	}
+}
+
+func testInZone(t *testing.T, testCase inZoneTestCase) {
+	assert.Equal(t, testCase.expected, InZone(testCase.fqdn, testCase.zone))
+}
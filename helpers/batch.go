@@ -0,0 +1,198 @@
+package helpers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+)
+
+// Operations that may appear in a batch.
+const (
+	BatchSet    = "set"
+	BatchAdd    = "add"
+	BatchRemove = "remove"
+	BatchClear  = "clear"
+)
+
+// BatchOperation is a single operation read from a batch.
+type BatchOperation struct {
+	// Line is the line of the batch on which the operation appeared.
+	Line int `json:"-"`
+	// Op is the operation to perform: set, add, remove, or clear.
+	Op string `json:"op"`
+	// Type is the record type, in upper case.
+	Type dns.RecordType `json:"type"`
+	// Name is the hostname of the record set, as given on the command line.
+	Name string `json:"name"`
+	// Values holds the records, in the same form as on the command line.
+	Values []string `json:"values"`
+	// TTL overrides the default TTL of the record set, if not nil.
+	TTL *int64 `json:"ttl"`
+}
+
+// BatchError reports the lines of a batch that could not be parsed.
+type BatchError struct {
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	messages := []string{}
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// ParseBatch reads a batch of operations from r, one per line. Blank lines and
+// lines starting with # are ignored. A line starting with { is a JSON object
+// with the fields of BatchOperation; any other line has the form "OP TYPE
+// HOSTNAME [VALUES...]" and is split into fields by SplitFields. If any line
+// is invalid, a *BatchError listing all of them is returned.
+func ParseBatch(r io.Reader) ([]BatchOperation, error) {
+	operations := []BatchOperation{}
+	errs := []error{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		operation, err := parseBatchLine(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %v: %v", line, err))
+			continue
+		}
+
+		operation.Line = line
+		operations = append(operations, operation)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(errs) > 0 {
+		return nil, &BatchError{Errors: errs}
+	}
+
+	return operations, nil
+}
+
+// parseBatchLine parses and validates a single non-empty line of a batch.
+func parseBatchLine(text string) (BatchOperation, error) {
+	var operation BatchOperation
+
+	if strings.HasPrefix(text, "{") {
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&operation); err != nil {
+			return operation, fmt.Errorf("invalid JSON: %v", err)
+		}
+	} else {
+		fields, err := SplitFields(text)
+		if err != nil {
+			return operation, err
+		}
+		if len(fields) < 3 {
+			return operation, fmt.Errorf("expected OP TYPE HOSTNAME [VALUES...]")
+		}
+
+		operation.Op = fields[0]
+		operation.Type = dns.RecordType(fields[1])
+		operation.Name = fields[2]
+		operation.Values = fields[3:]
+	}
+
+	operation.Op = strings.ToLower(operation.Op)
+	operation.Type = dns.RecordType(strings.ToUpper(string(operation.Type)))
+
+	if operation.Type == "" {
+		return operation, fmt.Errorf("a record type is required")
+	}
+
+	switch operation.Op {
+	case BatchSet, BatchAdd, BatchRemove:
+		if len(operation.Values) == 0 {
+			return operation, fmt.Errorf("%v requires at least one value", operation.Op)
+		}
+	case BatchClear:
+		if len(operation.Values) > 0 {
+			return operation, fmt.Errorf("clear does not take values")
+		}
+	case "":
+		return operation, fmt.Errorf("an operation is required")
+	default:
+		return operation, fmt.Errorf("unknown operation %q", operation.Op)
+	}
+
+	if operation.TTL != nil && *operation.TTL < 0 {
+		return operation, fmt.Errorf("invalid TTL %v", *operation.TTL)
+	}
+
+	return operation, nil
+}
+
+// SplitFields splits a line into fields separated by whitespace, in the manner
+// of a shell. Text within single quotes is taken literally; within double
+// quotes, and outside quotes, a backslash escapes the following character.
+// Quoting allows fields to contain whitespace or to be empty.
+func SplitFields(line string) ([]string, error) {
+	fields := []string{}
+
+	var field strings.Builder
+	inField := false
+	var quote rune
+	escaped := false
+
+	for _, c := range line {
+		switch {
+		case escaped:
+			field.WriteRune(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				field.WriteRune(c)
+			}
+		case c == '\\':
+			escaped, inField = true, true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				field.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote, inField = c, true
+		case c == ' ' || c == '\t':
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(c)
+			inField = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,94 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+type splitFieldsTestCase struct {
+	line   string
+	fields []string
+}
+
+var splitFieldsTests = []splitFieldsTestCase{
+	{"set A www 1.2.3.4", []string{"set", "A", "www", "1.2.3.4"}},
+	{"  set\tA  www  ", []string{"set", "A", "www"}},
+	{`set TXT www "hello world"`, []string{"set", "TXT", "www", "hello world"}},
+	{`set TXT www 'say "hi"'`, []string{"set", "TXT", "www", `say "hi"`}},
+	{`set TXT www "say \"hi\""`, []string{"set", "TXT", "www", `say "hi"`}},
+	{`set TXT www 'back\slash'`, []string{"set", "TXT", "www", `back\slash`}},
+	{`set TXT www semi\ colon`, []string{"set", "TXT", "www", "semi colon"}},
+	{`set A "" 1.2.3.4`, []string{"set", "A", "", "1.2.3.4"}},
+	{`set TXT www ab"c d"e`, []string{"set", "TXT", "www", "abc de"}},
+	{"", []string{}},
+	{`set TXT www "unterminated`, nil},
+	{`set TXT www 'unterminated`, nil},
+	{`set TXT www trailing\`, nil},
+}
+
+func TestSplitFields(t *testing.T) {
+	for _, testCase := range splitFieldsTests {
+		t.Run(testCase.line, func(t *testing.T) { testSplitFields(t, testCase) })
+	}
+}
+
+func testSplitFields(t *testing.T, testCase splitFieldsTestCase) {
+	fields, err := SplitFields(testCase.line)
+	if testCase.fields == nil {
+		assert.Error(t, err)
+		return
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, testCase.fields, fields)
+}
+
+const testBatch = `
+# Comments and blank lines are ignored
+
+set a www 1.2.3.4 5.6.7.8
+CLEAR TXT old
+{"op": "add", "type": "txt", "name": "_acme-challenge", "values": ["token"], "ttl": 60}
+remove MX @ 10 "mail.example.com"
+`
+
+func TestParseBatch(t *testing.T) {
+	operations, err := ParseBatch(strings.NewReader(testBatch))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []BatchOperation{
+		{Line: 4, Op: BatchSet, Type: dns.A, Name: "www", Values: []string{"1.2.3.4", "5.6.7.8"}},
+		{Line: 5, Op: BatchClear, Type: dns.TXT, Name: "old", Values: []string{}},
+		{Line: 6, Op: BatchAdd, Type: dns.TXT, Name: "_acme-challenge", Values: []string{"token"}, TTL: to.Int64Ptr(60)},
+		{Line: 7, Op: BatchRemove, Type: dns.MX, Name: "@", Values: []string{"10", "mail.example.com"}},
+	}, operations)
+}
+
+func TestParseBatchErrors(t *testing.T) {
+	batch := strings.Join([]string{
+		"set A www 1.2.3.4",
+		"set A www",
+		"clear A www 1.2.3.4",
+		"rename A www new",
+		"set A",
+		`set TXT www "unterminated`,
+		`{"op": "set", "type": "A", "name": "www", "value": "1.2.3.4"}`,
+		`{"op": "clear", "name": "www"}`,
+		`{"op": "set", "type": "A", "name": "www", "values": ["1.2.3.4"], "ttl": -1}`,
+	}, "\n")
+
+	_, err := ParseBatch(strings.NewReader(batch))
+	if assert.IsType(t, &BatchError{}, err) {
+		errs := err.(*BatchError).Errors
+		if assert.Len(t, errs, 8) {
+			assert.True(t, strings.HasPrefix(errs[0].Error(), "line 2: "))
+			assert.True(t, strings.HasPrefix(errs[7].Error(), "line 9: "))
+		}
+	}
+}
@@ -1,60 +1,73 @@
 package helpers
 
 import (
-	"fmt"
-
 	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
 	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/adal"
-	"github.com/Azure/go-autorest/autorest/azure"
-	authfile "github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/spf13/viper"
 )
 
-// NewRecordSetClient creates a new RecordSetsClient using the specified
-// baseURI and attaches a BearerAuthorizer based on credentials provided either
-// via an Azure SDK auth file, if present, or through any mechanism supported
-// by Viper. If credentials have not been provided, an error will be returned.
-func NewRecordSetClient(baseURI string) (*dns.RecordSetsClient, error) {
-	var authorizer *autorest.BearerAuthorizer
-	subscriptionID := viper.GetString("subscription-id")
-
-	if clientSetup, err := authfile.GetClientSetup(baseURI); err == nil {
-		authorizer = clientSetup.BearerAuthorizer
-		subscriptionID = clientSetup.SubscriptionID
-	} else {
-		authorizer, err = GetAuthorizer(baseURI)
-		if err != nil {
-			return nil, err
-		}
+// NewRecordSetClient creates a new RecordSetsClient for the specified cloud
+// and attaches a BearerAuthorizer based on credentials provided either via an
+// Azure SDK auth file, if present, or through any mechanism supported by
+// Viper. The authentication method may be chosen with the auth-method
+// option. If credentials have not been provided, an error will be returned.
+// Requests that fail transiently are retried according to GetRetryPolicy, and
+// if the dry-run option is set, only requests that read are sent.
+func NewRecordSetClient(cloud *Cloud) (*dns.RecordSetsClient, error) {
+	credentials, err := getClientCredentials(cloud)
+	if err != nil {
+		return nil, &CredentialsError{Err: err}
 	}
 
-	client := dns.NewRecordSetsClientWithBaseURI(baseURI, subscriptionID)
-	client.Authorizer = authorizer
+	client := dns.NewRecordSetsClientWithBaseURI(credentials.baseURI, credentials.subscriptionID)
+	client.Authorizer = credentials.authorizer
+	GetRetryPolicy().Apply(&client.Client)
+	applyDryRun(&client.Client)
 
 	return &client, nil
 }
 
-// GetAuthorizer creates a BearerAuthorizer based on credentials retrieved from
-// Viper. If credentials have not been provided, an error will be returned.
-func GetAuthorizer(baseURI string) (*autorest.BearerAuthorizer, error) {
-	credFields := []string{"client-id", "client-secret", "subscription-id", "tenant-id"}
-
-	for _, field := range credFields {
-		if !viper.IsSet(field) || viper.GetString(field) == "" {
-			return nil, fmt.Errorf("required credential option %v not provided", field)
-		}
+// NewZonesClient creates a new ZonesClient for the specified cloud and
+// attaches a BearerAuthorizer, retry policy, and dry-run mode in the same
+// manner as NewRecordSetClient.
+func NewZonesClient(cloud *Cloud) (*dns.ZonesClient, error) {
+	credentials, err := getClientCredentials(cloud)
+	if err != nil {
+		return nil, &CredentialsError{Err: err}
 	}
 
-	config, err := adal.NewOAuthConfig(azure.PublicCloud.ActiveDirectoryEndpoint, viper.GetString("tenant-id"))
+	client := dns.NewZonesClientWithBaseURI(credentials.baseURI, credentials.subscriptionID)
+	client.Authorizer = credentials.authorizer
+	GetRetryPolicy().Apply(&client.Client)
+	applyDryRun(&client.Client)
+
+	return &client, nil
+}
+
+// GetAuthorizer creates a BearerAuthorizer for a service principal in the
+// specified cloud that authenticates with the client secret retrieved from
+// Viper. If credentials have not been provided, an error will be returned.
+func GetAuthorizer(cloud *Cloud) (*autorest.BearerAuthorizer, error) {
+	token, err := newClientSecretToken(cloud)
 	if err != nil {
 		return nil, err
 	}
 
-	token, err := adal.NewServicePrincipalToken(*config, viper.GetString("client-id"), viper.GetString("client-secret"), baseURI)
+	return autorest.NewBearerAuthorizer(token), nil
+}
+
+// newClientSecretToken creates a token for a service principal that
+// authenticates with the client secret retrieved from Viper.
+func newClientSecretToken(cloud *Cloud) (*adal.ServicePrincipalToken, error) {
+	if err := requireCredentialOptions("client-id", "client-secret", "subscription-id", "tenant-id"); err != nil {
+		return nil, err
+	}
+
+	config, err := newOAuthConfig(cloud)
 	if err != nil {
 		return nil, err
 	}
 
-	return autorest.NewBearerAuthorizer(token), nil
+	return adal.NewServicePrincipalToken(*config, viper.GetString("client-id"), viper.GetString("client-secret"), cloud.TokenAudience)
 }
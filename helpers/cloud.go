@@ -0,0 +1,72 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/spf13/viper"
+)
+
+// Cloud holds the endpoints used to manage Azure DNS in an Azure cloud.
+type Cloud struct {
+	azure.Environment
+	// TokenAudience is the resource for which access tokens are requested.
+	TokenAudience string
+}
+
+// GetCloud returns the cloud selected by the cloud option, which may be the
+// name of an Azure environment, such as AzureChinaCloud or just China, or the
+// path to a JSON file describing a custom environment, such as an Azure Stack
+// instance. By default, the Azure public cloud is used. The token-audience
+// option overrides the audience of access tokens, which is otherwise the
+// environment's resource manager endpoint.
+func GetCloud() (*Cloud, error) {
+	env, err := getEnvironment(viper.GetString("cloud"))
+	if err != nil {
+		return nil, err
+	}
+
+	if env.ResourceManagerEndpoint == "" || env.ActiveDirectoryEndpoint == "" {
+		return nil, fmt.Errorf("the %v environment must have a resourceManagerEndpoint and an activeDirectoryEndpoint", env.Name)
+	}
+
+	audience := viper.GetString("token-audience")
+	if audience == "" {
+		audience = env.ResourceManagerEndpoint
+	}
+
+	return &Cloud{Environment: env, TokenAudience: audience}, nil
+}
+
+// getEnvironment returns the Azure environment with the given name, or stored
+// in the file at the given path.
+func getEnvironment(name string) (azure.Environment, error) {
+	if name == "" {
+		return azure.PublicCloud, nil
+	}
+
+	if info, err := os.Stat(name); err == nil && !info.IsDir() {
+		env, err := azure.EnvironmentFromFile(name)
+		if err != nil {
+			return env, fmt.Errorf("unable to read cloud environment file %v: %v", name, err)
+		}
+		if env.Name == "" {
+			env.Name = name
+		}
+		return env, nil
+	}
+
+	if env, err := azure.EnvironmentFromName(name); err == nil {
+		return env, nil
+	} else if strings.EqualFold(name, "AzureStackCloud") {
+		return env, fmt.Errorf("AzureStackCloud requires an environment file in $%v: %v", azure.EnvironmentFilepathName, err)
+	}
+
+	if env, err := azure.EnvironmentFromName("Azure" + name + "Cloud"); err == nil {
+		return env, nil
+	}
+
+	return azure.Environment{}, fmt.Errorf("unknown cloud %q; must be AzurePublicCloud, AzureChinaCloud, AzureUSGovernmentCloud, AzureGermanCloud, AzureStackCloud, or the path to an environment file", name)
+}
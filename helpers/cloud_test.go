@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+type getCloudTestCase struct {
+	name             string
+	cloud            string
+	audience         string
+	resourceManager  string
+	activeDirectory  string
+	expectedAudience string
+}
+
+var getCloudTests = []getCloudTestCase{
+	{"default", "", "", azure.PublicCloud.ResourceManagerEndpoint, azure.PublicCloud.ActiveDirectoryEndpoint, azure.PublicCloud.ResourceManagerEndpoint},
+	{"full name", "AzureChinaCloud", "", azure.ChinaCloud.ResourceManagerEndpoint, azure.ChinaCloud.ActiveDirectoryEndpoint, azure.ChinaCloud.ResourceManagerEndpoint},
+	{"short name", "usgovernment", "", azure.USGovernmentCloud.ResourceManagerEndpoint, azure.USGovernmentCloud.ActiveDirectoryEndpoint, azure.USGovernmentCloud.ResourceManagerEndpoint},
+	{"audience", "public", "https://management.core.windows.net/", azure.PublicCloud.ResourceManagerEndpoint, azure.PublicCloud.ActiveDirectoryEndpoint, "https://management.core.windows.net/"},
+	{"environment file", "testdata/azurestack.json", "", "https://management.local.azurestack.external/", "https://adfs.local.azurestack.external/adfs/", "https://management.local.azurestack.external/"},
+	{"unknown name", "AzureMoonCloud", "", "", "", ""},
+	{"incomplete environment file", "testdata/include.zone", "", "", "", ""},
+}
+
+func TestGetCloud(t *testing.T) {
+	for _, testCase := range getCloudTests {
+		t.Run(testCase.name, func(t *testing.T) { testGetCloud(t, testCase) })
+	}
+}
+
+func testGetCloud(t *testing.T, testCase getCloudTestCase) {
+	defer viper.Reset()
+	viper.Set("cloud", testCase.cloud)
+	viper.Set("token-audience", testCase.audience)
+
+	cloud, err := GetCloud()
+	if testCase.resourceManager == "" {
+		assert.Error(t, err)
+		return
+	}
+
+	if assert.NoError(t, err) {
+		assert.Equal(t, testCase.resourceManager, cloud.ResourceManagerEndpoint)
+		assert.Equal(t, testCase.activeDirectory, cloud.ActiveDirectoryEndpoint)
+		assert.Equal(t, testCase.expectedAudience, cloud.TokenAudience)
+	}
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// DefaultConflictRetries is the number of times ModifyRecordSet retries a
+// modification that conflicts with a concurrent change, unless told otherwise.
+const DefaultConflictRetries = 5
+
+// ConflictBackoff is the delay before the first retry of a conflicting
+// modification. It doubles with each subsequent retry.
+var ConflictBackoff = 500 * time.Millisecond
+
+// ConflictError indicates that a record set could not be modified because it
+// was changed by someone else after it was read.
+type ConflictError struct {
+	Name     string
+	Type     dns.RecordType
+	Attempts int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v record set %v was modified concurrently; gave up after %v attempts", e.Type, e.Name, e.Attempts)
+}
+
+// RecordSetModifier computes the desired state of a record set from its current
+// state. current is nil if the record set does not exist, and returning nil
+// indicates that the record set should be deleted, or not created. Returning
+// current itself indicates that no change is needed.
+type RecordSetModifier func(current *dns.RecordSet) (*dns.RecordSet, error)
+
+// ModifyRecordSet performs an optimistically-concurrent read-modify-write of a
+// record set. It reads the record set and its etag, passes it to modify, and
+// writes the result only if the record set has not changed in the meantime. If
+// it has, the whole cycle is repeated, with exponential backoff, up to retries
+// more times before a *ConflictError is returned. The result is the record set
+// as written, or as read if no change was needed, or nil if it was deleted or
+// never existed.
+func ModifyRecordSet(ctx context.Context, client *dns.RecordSetsClient, resourceGroup string, zone string, name string,
+	recordType dns.RecordType, retries int, modify RecordSetModifier) (*dns.RecordSet, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := modifyRecordSetOnce(ctx, client, resourceGroup, zone, name, recordType, modify)
+		if !IsPreconditionFailed(err) {
+			return result, err
+		}
+
+		if attempt >= retries {
+			return nil, &ConflictError{Name: name, Type: recordType, Attempts: attempt + 1}
+		}
+
+		select {
+		case <-time.After(ConflictBackoff << uint(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// modifyRecordSetOnce performs a single read-modify-write cycle for
+// ModifyRecordSet.
+func modifyRecordSetOnce(ctx context.Context, client *dns.RecordSetsClient, resourceGroup string, zone string, name string,
+	recordType dns.RecordType, modify RecordSetModifier) (*dns.RecordSet, error) {
+	var current *dns.RecordSet
+	rrset, err := client.Get(ctx, resourceGroup, zone, name, recordType)
+	if err == nil {
+		current = &rrset
+	} else if ResponseStatusCode(err) != http.StatusNotFound {
+		return nil, err
+	}
+
+	desired, err := modify(current)
+	if err != nil {
+		return nil, err
+	}
+
+	if desired == current {
+		return current, nil
+	}
+
+	if desired == nil {
+		_, err := client.Delete(ctx, resourceGroup, zone, name, recordType, to.String(current.Etag))
+		return nil, err
+	}
+
+	ifMatch, ifNoneMatch := "", "*"
+	if current != nil {
+		ifMatch, ifNoneMatch = to.String(current.Etag), ""
+	}
+
+	result, err := client.CreateOrUpdate(ctx, resourceGroup, zone, name, recordType, *desired, ifMatch, ifNoneMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// PreviewRecordSet reads a record set and passes it to modify, as
+// ModifyRecordSet would, but writes nothing. It returns the record set as read
+// and as it would be written, either of which is nil if the record set does not
+// exist or would be deleted.
+func PreviewRecordSet(ctx context.Context, client *dns.RecordSetsClient, resourceGroup string, zone string, name string,
+	recordType dns.RecordType, modify RecordSetModifier) (current *dns.RecordSet, desired *dns.RecordSet, err error) {
+	rrset, err := client.Get(ctx, resourceGroup, zone, name, recordType)
+	if err == nil {
+		current = &rrset
+	} else if ResponseStatusCode(err) != http.StatusNotFound {
+		return nil, nil, err
+	}
+
+	desired, err = modify(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return current, desired, nil
+}
+
+// ResponseStatusCode returns the HTTP status code of the response that caused
+// an Azure API call to fail, or 0 if err was not caused by an HTTP response.
+func ResponseStatusCode(err error) int {
+	if detailed, ok := err.(autorest.DetailedError); ok {
+		if code, ok := detailed.StatusCode.(int); ok {
+			return code
+		}
+	}
+
+	return 0
+}
+
+// IsPreconditionFailed reports whether err was caused by an If-Match or
+// If-None-Match condition not being met.
+func IsPreconditionFailed(err error) bool {
+	return ResponseStatusCode(err) == http.StatusPreconditionFailed
+}
+
+// IsConflict reports whether err indicates that a record set was changed
+// concurrently, either because a conditional request failed or because
+// ModifyRecordSet gave up retrying.
+func IsConflict(err error) bool {
+	if _, ok := err.(*ConflictError); ok {
+		return true
+	}
+
+	return IsPreconditionFailed(err)
+}
@@ -0,0 +1,236 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRecordSetServer is a minimal stand-in for the Azure DNS API that holds a
+// single record set and honors the If-Match and If-None-Match headers.
+type fakeRecordSetServer struct {
+	mu      sync.Mutex
+	rrset   *dns.RecordSet
+	version int
+	// conflicts is the number of writes that will fail as though the record
+	// set had been changed by someone else just before they arrived.
+	conflicts int
+	requests  []string
+}
+
+func (f *fakeRecordSetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	f.requests = append(f.requests, fmt.Sprintf("%v %v %v", r.Method, ifMatch, ifNoneMatch))
+
+	if r.Method != http.MethodGet && f.conflicts > 0 {
+		f.conflicts--
+		f.version++
+		if f.rrset != nil {
+			f.rrset.Etag = to.StringPtr(fmt.Sprint(f.version))
+		}
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	etag := ""
+	if f.rrset != nil {
+		etag = to.String(f.rrset.Etag)
+	}
+	if (ifMatch != "" && ifMatch != etag) || (ifNoneMatch == "*" && f.rrset != nil) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if f.rrset == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+	case http.MethodPut:
+		rrset := dns.RecordSet{}
+		if err := json.NewDecoder(r.Body).Decode(&rrset); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.version++
+		rrset.Etag = to.StringPtr(fmt.Sprint(f.version))
+		f.rrset = &rrset
+	case http.MethodDelete:
+		f.rrset = nil
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f.rrset)
+}
+
+func newFakeRecordSetClient(fake *fakeRecordSetServer) (*dns.RecordSetsClient, func()) {
+	backoff := ConflictBackoff
+	ConflictBackoff = time.Millisecond
+
+	server := httptest.NewServer(fake)
+	client := dns.NewRecordSetsClientWithBaseURI(server.URL, "subscription")
+
+	return &client, func() {
+		server.Close()
+		ConflictBackoff = backoff
+	}
+}
+
+func setARecords(addresses ...string) RecordSetModifier {
+	return func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		records := []dns.ARecord{}
+		for _, address := range addresses {
+			records = append(records, dns.ARecord{Ipv4Address: to.StringPtr(address)})
+		}
+
+		return &dns.RecordSet{
+			RecordSetProperties: &dns.RecordSetProperties{
+				TTL:      to.Int64Ptr(300),
+				ARecords: &records,
+			},
+		}, nil
+	}
+}
+
+func TestModifyRecordSetCreate(t *testing.T) {
+	fake := &fakeRecordSetServer{}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	result, err := ModifyRecordSet(context.Background(), client, "rg", "example.com", "www", dns.A, 0, setARecords("192.0.2.1"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, []string{"192.0.2.1"}, RecordValues(dns.A, result.RecordSetProperties))
+	}
+	assert.Equal(t, []string{"GET  ", "PUT  *"}, fake.requests)
+}
+
+func TestModifyRecordSetRetriesConflicts(t *testing.T) {
+	fake := &fakeRecordSetServer{conflicts: 2}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	var seen []*dns.RecordSet
+	modify := func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		seen = append(seen, current)
+		return setARecords("192.0.2.1")(current)
+	}
+
+	_, err := ModifyRecordSet(context.Background(), client, "rg", "example.com", "www", dns.A, 2, modify)
+	assert.NoError(t, err)
+	assert.Len(t, seen, 3)
+	assert.Equal(t, []string{"GET  ", "PUT  *", "GET  ", "PUT  *", "GET  ", "PUT  *"}, fake.requests)
+}
+
+func TestModifyRecordSetGivesUp(t *testing.T) {
+	fake := &fakeRecordSetServer{conflicts: 10}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	_, err := ModifyRecordSet(context.Background(), client, "rg", "example.com", "www", dns.A, 1, setARecords("192.0.2.1"))
+	assert.IsType(t, &ConflictError{}, err)
+	assert.True(t, IsConflict(err))
+	assert.Len(t, fake.requests, 4)
+}
+
+func TestModifyRecordSetUsesEtag(t *testing.T) {
+	fake := &fakeRecordSetServer{}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	ctx := context.Background()
+	_, err := ModifyRecordSet(ctx, client, "rg", "example.com", "www", dns.A, 0, setARecords("192.0.2.1"))
+	assert.NoError(t, err)
+
+	fake.requests = nil
+	_, err = ModifyRecordSet(ctx, client, "rg", "example.com", "www", dns.A, 0, setARecords("192.0.2.2"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GET  ", "PUT 1 "}, fake.requests)
+
+	fake.requests = nil
+	result, err := ModifyRecordSet(ctx, client, "rg", "example.com", "www", dns.A, 0, func(*dns.RecordSet) (*dns.RecordSet, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Nil(t, fake.rrset)
+	assert.Equal(t, []string{"GET  ", "DELETE 2 "}, fake.requests)
+}
+
+func TestModifyRecordSetDeleteRetriesConflicts(t *testing.T) {
+	fake := &fakeRecordSetServer{
+		rrset:     &dns.RecordSet{Etag: to.StringPtr("0")},
+		conflicts: 1,
+	}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	result, err := ModifyRecordSet(context.Background(), client, "rg", "example.com", "www", dns.A, 1, func(*dns.RecordSet) (*dns.RecordSet, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Nil(t, fake.rrset)
+	assert.Equal(t, []string{"GET  ", "DELETE 0 ", "GET  ", "DELETE 1 "}, fake.requests)
+}
+
+func TestModifyRecordSetNoChange(t *testing.T) {
+	fake := &fakeRecordSetServer{}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	ctx := context.Background()
+	unchanged := func(current *dns.RecordSet) (*dns.RecordSet, error) {
+		return current, nil
+	}
+
+	result, err := ModifyRecordSet(ctx, client, "rg", "example.com", "www", dns.A, 0, unchanged)
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, []string{"GET  "}, fake.requests)
+
+	_, err = ModifyRecordSet(ctx, client, "rg", "example.com", "www", dns.A, 0, setARecords("192.0.2.1"))
+	assert.NoError(t, err)
+
+	fake.requests = nil
+	result, err = ModifyRecordSet(ctx, client, "rg", "example.com", "www", dns.A, 0, unchanged)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "1", to.String(result.Etag))
+	}
+	assert.Equal(t, []string{"GET  "}, fake.requests)
+}
+
+func TestModifyRecordSetModifierError(t *testing.T) {
+	fake := &fakeRecordSetServer{}
+	client, done := newFakeRecordSetClient(fake)
+	defer done()
+
+	_, err := ModifyRecordSet(context.Background(), client, "rg", "example.com", "www", dns.A, 0, func(*dns.RecordSet) (*dns.RecordSet, error) {
+		return nil, fmt.Errorf("refused")
+	})
+	assert.EqualError(t, err, "refused")
+	assert.Equal(t, []string{"GET  "}, fake.requests)
+}
+
+func TestIsConflict(t *testing.T) {
+	assert.True(t, IsConflict(&ConflictError{}))
+	assert.False(t, IsConflict(fmt.Errorf("other")))
+	assert.False(t, IsConflict(nil))
+}
@@ -0,0 +1,177 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Keys with special meaning in the config file.
+const (
+	// ProfileKey selects the profile to use when none is given otherwise.
+	ProfileKey = "profile"
+	// ProfilesKey holds the named profiles, each of which may contain any
+	// setting other than profile and profiles.
+	ProfilesKey = "profiles"
+	// ZonesKey holds per-zone defaults, keyed by zone name, at the top level
+	// or within a profile.
+	ZonesKey = "zones"
+)
+
+// RedactedValue replaces the values of secret settings in output.
+const RedactedValue = "REDACTED"
+
+// secretSettings lists the settings whose values are redacted by
+// RedactSettings.
+var secretSettings = []string{"client-secret", "client-certificate-password"}
+
+// NormalizeSettings converts the maps in settings parsed from a config file
+// to map[string]interface{} with lowercase keys, matching how Viper treats
+// keys, and removes trailing dots from the names of zones. Other values are
+// returned unchanged.
+func NormalizeSettings(settings interface{}) interface{} {
+	switch settings := settings.(type) {
+	case map[string]interface{}:
+		normalized := map[string]interface{}{}
+		for key, value := range settings {
+			normalized[strings.ToLower(key)] = NormalizeSettings(value)
+		}
+		return normalizeZoneNames(normalized)
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for key, value := range settings {
+			normalized[strings.ToLower(fmt.Sprint(key))] = NormalizeSettings(value)
+		}
+		return normalizeZoneNames(normalized)
+	case []interface{}:
+		normalized := make([]interface{}, len(settings))
+		for i, value := range settings {
+			normalized[i] = NormalizeSettings(value)
+		}
+		return normalized
+	default:
+		return settings
+	}
+}
+
+// normalizeZoneNames removes trailing dots from the names of the zones in
+// settings, so that the defaults for a zone are found under a single key.
+func normalizeZoneNames(settings map[string]interface{}) map[string]interface{} {
+	zones, ok := settings[ZonesKey].(map[string]interface{})
+	if !ok {
+		return settings
+	}
+
+	normalized := map[string]interface{}{}
+	for name, value := range zones {
+		normalized[normalizeZoneName(name)] = value
+	}
+	settings[ZonesKey] = normalized
+
+	return settings
+}
+
+// MergeSettings returns a copy of base with the settings in overlay added to
+// it. Maps present in both are merged recursively; any other value in overlay
+// replaces the one in base.
+func MergeSettings(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range overlay {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overlayMap, overlayIsMap := value.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[key] = MergeSettings(baseMap, overlayMap)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+// ProfileNames returns the sorted names of the profiles in settings.
+func ProfileNames(settings map[string]interface{}) []string {
+	profiles, _ := settings[ProfilesKey].(map[string]interface{})
+
+	names := []string{}
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// ProfileSettings returns the settings that apply when the named profile is
+// used: those at the top level of settings, overridden by those in the
+// profile. An error is returned if there is no such profile.
+func ProfileSettings(settings map[string]interface{}, profile string) (map[string]interface{}, error) {
+	profiles, _ := settings[ProfilesKey].(map[string]interface{})
+	profileSettings, ok := profiles[strings.ToLower(profile)].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in the config file", profile)
+	}
+
+	return MergeSettings(settings, profileSettings), nil
+}
+
+// ZoneSettings returns the defaults for the named zone in settings, which must
+// have been normalized, or nil if there are none. Zone names are matched
+// without regard to case or a trailing dot.
+func ZoneSettings(settings map[string]interface{}, zone string) map[string]interface{} {
+	zones, _ := settings[ZonesKey].(map[string]interface{})
+	zoneSettings, _ := zones[normalizeZoneName(zone)].(map[string]interface{})
+
+	return zoneSettings
+}
+
+func normalizeZoneName(zone string) string {
+	return strings.TrimRight(strings.ToLower(zone), ".")
+}
+
+// SetSetting sets key to value in settings, within the maps named by path,
+// which are created as needed. An error is returned if an element of path
+// exists but is not a map.
+func SetSetting(settings map[string]interface{}, path []string, key string, value interface{}) error {
+	section := settings
+	for _, name := range path {
+		name = strings.ToLower(name)
+		if section[name] == nil {
+			section[name] = map[string]interface{}{}
+		}
+
+		next, ok := section[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("config setting %v is not a map", name)
+		}
+		section = next
+	}
+
+	section[strings.ToLower(key)] = value
+	return nil
+}
+
+// RedactSettings returns a copy of settings with the values of secrets, at any
+// depth, replaced by RedactedValue.
+func RedactSettings(settings map[string]interface{}) map[string]interface{} {
+	redacted := map[string]interface{}{}
+	for key, value := range settings {
+		if nested, ok := value.(map[string]interface{}); ok {
+			redacted[key] = RedactSettings(nested)
+			continue
+		}
+
+		redacted[key] = value
+		for _, secret := range secretSettings {
+			if key == secret && value != "" {
+				redacted[key] = RedactedValue
+			}
+		}
+	}
+
+	return redacted
+}
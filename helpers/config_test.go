@@ -0,0 +1,138 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testConfig = `
+Tenant-ID: tenant
+client-secret: top-secret
+profile: prod
+zones:
+  example.com:
+    resource-group: dns
+profiles:
+  prod:
+    subscription-id: prod-subscription
+    client-secret: prod-secret
+    zones:
+      Example.COM.:
+        resource-group: dns-prod
+      prod.example.com:
+        resource-group: dns-prod
+  staging:
+    subscription-id: staging-subscription
+`
+
+func parseTestConfig(t *testing.T) map[string]interface{} {
+	var settings interface{}
+	if err := yaml.Unmarshal([]byte(testConfig), &settings); err != nil {
+		t.Fatal(err)
+	}
+
+	return NormalizeSettings(settings).(map[string]interface{})
+}
+
+func TestNormalizeSettings(t *testing.T) {
+	settings := parseTestConfig(t)
+
+	assert.Equal(t, "tenant", settings["tenant-id"])
+	assert.Equal(t, map[string]interface{}{"resource-group": "dns"}, settings["zones"].(map[string]interface{})["example.com"])
+	assert.Equal(t, []string{"prod", "staging"}, ProfileNames(settings))
+	assert.Equal(t, []string{}, ProfileNames(map[string]interface{}{}))
+}
+
+func TestProfileSettings(t *testing.T) {
+	settings := parseTestConfig(t)
+
+	prod, err := ProfileSettings(settings, "PROD")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "tenant", prod["tenant-id"])
+		assert.Equal(t, "prod-subscription", prod["subscription-id"])
+		assert.Equal(t, "prod-secret", prod["client-secret"])
+		assert.Len(t, prod["zones"], 2)
+	}
+
+	staging, err := ProfileSettings(settings, "staging")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "staging-subscription", staging["subscription-id"])
+		assert.Equal(t, "top-secret", staging["client-secret"])
+	}
+
+	_, err = ProfileSettings(settings, "test")
+	assert.Error(t, err)
+
+	// The original settings are unchanged.
+	assert.Nil(t, settings["subscription-id"])
+	assert.Len(t, settings["zones"], 1)
+}
+
+type zoneSettingsTestCase struct {
+	name          string
+	profile       string
+	zone          string
+	resourceGroup interface{}
+}
+
+var zoneSettingsTests = []zoneSettingsTestCase{
+	{"top level", "", "example.com", "dns"},
+	{"trailing dot", "", "example.com.", "dns"},
+	{"missing", "", "prod.example.com", nil},
+	{"profile overrides top level", "prod", "EXAMPLE.com", "dns-prod"},
+	{"profile only", "prod", "prod.example.com", "dns-prod"},
+	{"profile without zones", "staging", "example.com", "dns"},
+}
+
+func TestZoneSettings(t *testing.T) {
+	for _, testCase := range zoneSettingsTests {
+		t.Run(testCase.name, func(t *testing.T) { testZoneSettings(t, testCase) })
+	}
+}
+
+func testZoneSettings(t *testing.T, testCase zoneSettingsTestCase) {
+	settings := parseTestConfig(t)
+	if testCase.profile != "" {
+		var err error
+		if settings, err = ProfileSettings(settings, testCase.profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, testCase.resourceGroup, ZoneSettings(settings, testCase.zone)["resource-group"])
+}
+
+func TestSetSetting(t *testing.T) {
+	settings := parseTestConfig(t)
+
+	assert.NoError(t, SetSetting(settings, nil, "Client-ID", "client"))
+	assert.NoError(t, SetSetting(settings, []string{ProfilesKey, "test"}, "subscription-id", "test-subscription"))
+	assert.NoError(t, SetSetting(settings, []string{ProfilesKey, "prod", ZonesKey, "new.example.com"}, "resource-group", "dns-new"))
+	assert.Error(t, SetSetting(settings, []string{"tenant-id"}, "client-id", "client"))
+
+	assert.Equal(t, "client", settings["client-id"])
+	test, err := ProfileSettings(settings, "test")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "test-subscription", test["subscription-id"])
+	}
+	prod, err := ProfileSettings(settings, "prod")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "dns-new", ZoneSettings(prod, "new.example.com")["resource-group"])
+		assert.Equal(t, "dns-prod", ZoneSettings(prod, "prod.example.com")["resource-group"])
+	}
+}
+
+func TestRedactSettings(t *testing.T) {
+	settings := parseTestConfig(t)
+	redacted := RedactSettings(settings)
+
+	assert.Equal(t, RedactedValue, redacted["client-secret"])
+	assert.Equal(t, "tenant", redacted["tenant-id"])
+	prod := redacted[ProfilesKey].(map[string]interface{})["prod"].(map[string]interface{})
+	assert.Equal(t, RedactedValue, prod["client-secret"])
+	assert.Equal(t, "prod-subscription", prod["subscription-id"])
+
+	assert.Equal(t, "top-secret", settings["client-secret"])
+}
@@ -0,0 +1,780 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	authfile "github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/spf13/viper"
+)
+
+// Authentication methods that may be selected with the auth-method option.
+const (
+	AuthMethodAuto              = "auto"
+	AuthMethodAuthFile          = "auth-file"
+	AuthMethodClientSecret      = "client-secret"
+	AuthMethodClientCertificate = "client-certificate"
+	AuthMethodFederatedToken    = "federated-token"
+	AuthMethodMSI               = "msi"
+	AuthMethodCLI               = "cli"
+)
+
+// AuthMethods lists the authentication methods that may be selected with the
+// auth-method option. Those after AuthMethodAuto are listed in the order in
+// which it tries them.
+var AuthMethods = []string{
+	AuthMethodAuto,
+	AuthMethodAuthFile,
+	AuthMethodClientSecret,
+	AuthMethodClientCertificate,
+	AuthMethodFederatedToken,
+	AuthMethodMSI,
+	AuthMethodCLI,
+}
+
+// azureCLICommand is the name of the Azure CLI executable.
+var azureCLICommand = "az"
+
+// clientCredentials holds what is needed to make requests to Azure Resource
+// Manager on behalf of a security principal.
+type clientCredentials struct {
+	authorizer     autorest.Authorizer
+	subscriptionID string
+	// baseURI is the Azure Resource Manager endpoint to which requests are
+	// sent.
+	baseURI string
+}
+
+// getClientCredentials returns the credentials for the cloud using the
+// authentication method selected by the auth-method option. By default, an
+// Azure SDK auth file is used if present, and otherwise each method for which
+// options have been provided is tried in turn; see getChainedCredentials.
+func getClientCredentials(cloud *Cloud) (*clientCredentials, error) {
+	method := viper.GetString("auth-method")
+	switch method {
+	case "", AuthMethodAuto:
+		if credentials, err := getAuthFileCredentials(); err == nil {
+			return credentials, nil
+		}
+		return getChainedCredentials(cloud)
+	case AuthMethodAuthFile:
+		return getAuthFileCredentials()
+	}
+
+	token, err := newAccessToken(cloud, method)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenCredentials(cloud, token), nil
+}
+
+// newTokenCredentials returns credentials that authenticate with token. The
+// subscription is given by the subscription-id option, or for the Azure CLI,
+// defaults to the CLI's current subscription.
+func newTokenCredentials(cloud *Cloud, token contextToken) *clientCredentials {
+	subscriptionID := viper.GetString("subscription-id")
+	if cli, ok := token.(*cliToken); ok {
+		subscriptionID = cli.subscription
+	}
+
+	return &clientCredentials{
+		authorizer:     &tokenAuthorizer{token: token},
+		subscriptionID: subscriptionID,
+		baseURI:        cloud.ResourceManagerEndpoint,
+	}
+}
+
+// newAccessToken returns the access token for the cloud obtained with the
+// given authentication method, other than an auth file.
+func newAccessToken(cloud *Cloud, method string) (contextToken, error) {
+	switch method {
+	case AuthMethodClientSecret:
+		return newServicePrincipalToken(newClientSecretToken(cloud))
+	case AuthMethodClientCertificate:
+		return newServicePrincipalToken(newCertificateToken(cloud))
+	case AuthMethodFederatedToken:
+		return newServicePrincipalToken(newFederatedToken(cloud))
+	case AuthMethodMSI:
+		return newMSIToken(cloud)
+	case AuthMethodCLI:
+		return newCLIToken(cloud)
+	}
+
+	return nil, fmt.Errorf("unknown auth method %q; must be one of %v", method, strings.Join(AuthMethods, ", "))
+}
+
+// getAuthFileCredentials returns the credentials in the Azure SDK auth file
+// given by $AZURE_AUTH_LOCATION. The endpoints in the file are used instead of
+// those of the cloud selected by the cloud option.
+func getAuthFileCredentials() (*clientCredentials, error) {
+	// GetClientSetup looks up the endpoint in the auth file that corresponds
+	// to the given public cloud endpoint.
+	clientSetup, err := authfile.GetClientSetup(azure.PublicCloud.ResourceManagerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clientCredentials{
+		authorizer:     clientSetup.BearerAuthorizer,
+		subscriptionID: clientSetup.SubscriptionID,
+		baseURI:        clientSetup.ResourceManagerEndpoint,
+	}, nil
+}
+
+// configuredAuthMethods returns, in the order in which they are tried, the
+// authentication methods other than an auth file for which the necessary
+// options have been provided. A managed identity is only tried if the
+// msi-endpoint option is set or the Instance Metadata Service responds, and
+// the Azure CLI only if it is installed.
+func configuredAuthMethods() []string {
+	methods := []string{}
+	if viper.GetString("client-secret") != "" {
+		methods = append(methods, AuthMethodClientSecret)
+	}
+	if viper.GetString("client-certificate") != "" {
+		methods = append(methods, AuthMethodClientCertificate)
+	}
+	if viper.GetString("federated-token-file") != "" {
+		methods = append(methods, AuthMethodFederatedToken)
+	}
+
+	if viper.GetString("msi-endpoint") != "" || imdsAvailable() {
+		methods = append(methods, AuthMethodMSI)
+	}
+
+	if _, err := exec.LookPath(azureCLICommand); err == nil {
+		methods = append(methods, AuthMethodCLI)
+	}
+
+	return methods
+}
+
+// requireCredentialOptions returns an error if any of the given options has
+// not been provided.
+func requireCredentialOptions(options ...string) error {
+	for _, option := range options {
+		if viper.GetString(option) == "" {
+			return fmt.Errorf("required credential option %v not provided", option)
+		}
+	}
+
+	return nil
+}
+
+// newOAuthConfig returns the OAuth configuration for the tenant given by the
+// tenant-id option in the cloud.
+func newOAuthConfig(cloud *Cloud) (*adal.OAuthConfig, error) {
+	return adal.NewOAuthConfig(cloud.ActiveDirectoryEndpoint, viper.GetString("tenant-id"))
+}
+
+// newCertificateToken creates a token for a service principal that
+// authenticates with the certificate and private key in the PEM file given by
+// the client-certificate option.
+func newCertificateToken(cloud *Cloud) (*adal.ServicePrincipalToken, error) {
+	if err := requireCredentialOptions("client-id", "client-certificate", "subscription-id", "tenant-id"); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(viper.GetString("client-certificate"))
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, privateKey, err := ParseCertificate(data, viper.GetString("client-certificate-password"))
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := newOAuthConfig(cloud)
+	if err != nil {
+		return nil, err
+	}
+
+	return adal.NewServicePrincipalTokenFromCertificate(*config, viper.GetString("client-id"), certificate, privateKey, cloud.TokenAudience)
+}
+
+// ParseCertificate returns the first certificate and the RSA private key in
+// PEM-encoded data. A private key encrypted with a password in the
+// traditional OpenSSL format is decrypted with password.
+func ParseCertificate(data []byte, password string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	var certificate *x509.Certificate
+	var privateKey *rsa.PrivateKey
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			if certificate != nil {
+				continue
+			}
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, err
+			}
+			certificate = parsed
+		case "RSA PRIVATE KEY", "PRIVATE KEY":
+			parsed, err := parsePrivateKey(block, password)
+			if err != nil {
+				return nil, nil, err
+			}
+			privateKey = parsed
+		case "ENCRYPTED PRIVATE KEY":
+			return nil, nil, fmt.Errorf("encrypted PKCS #8 private keys are not supported")
+		}
+	}
+
+	if certificate == nil {
+		return nil, nil, fmt.Errorf("no certificate found")
+	}
+	if privateKey == nil {
+		return nil, nil, fmt.Errorf("no RSA private key found")
+	}
+
+	publicKey, ok := certificate.PublicKey.(*rsa.PublicKey)
+	if !ok || publicKey.N.Cmp(privateKey.N) != 0 || publicKey.E != privateKey.E {
+		return nil, nil, fmt.Errorf("the private key does not match the certificate")
+	}
+
+	return certificate, privateKey, nil
+}
+
+// parsePrivateKey parses an RSA private key in PKCS #1 or PKCS #8 form,
+// decrypting it first if necessary.
+func parsePrivateKey(block *pem.Block, password string) (*rsa.PrivateKey, error) {
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if password == "" {
+			return nil, fmt.Errorf("the private key is encrypted, but no password was provided")
+		}
+
+		var err error
+		if der, err = x509.DecryptPEMBlock(block, []byte(password)); err != nil {
+			return nil, err
+		}
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// federatedTokenSecret authenticates a service principal with a token issued
+// by a federated identity provider, such as the service account tokens
+// projected into Kubernetes pods for Azure AD workload identity. The token is
+// read from its file each time it is needed, since it is rotated.
+type federatedTokenSecret struct {
+	path string
+}
+
+// SetAuthenticationValues implements adal.ServicePrincipalSecret.
+func (secret *federatedTokenSecret) SetAuthenticationValues(spt *adal.ServicePrincipalToken, v *url.Values) error {
+	assertion, err := ioutil.ReadFile(secret.path)
+	if err != nil {
+		return err
+	}
+
+	v.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	v.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	return nil
+}
+
+// newFederatedToken creates a token for a service principal that
+// authenticates with the federated token in the file given by the
+// federated-token-file option.
+func newFederatedToken(cloud *Cloud) (*adal.ServicePrincipalToken, error) {
+	if err := requireCredentialOptions("client-id", "federated-token-file", "subscription-id", "tenant-id"); err != nil {
+		return nil, err
+	}
+
+	config, err := newOAuthConfig(cloud)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &federatedTokenSecret{path: viper.GetString("federated-token-file")}
+	return adal.NewServicePrincipalTokenWithSecret(*config, viper.GetString("client-id"), cloud.TokenAudience, secret)
+}
+
+// servicePrincipalToken adapts an adal.ServicePrincipalToken to contextToken.
+// The version of adal in use cannot cancel its requests, so ctx is only
+// checked before refreshing.
+type servicePrincipalToken struct {
+	*adal.ServicePrincipalToken
+}
+
+// newServicePrincipalToken wraps the result of one of the functions that
+// create an adal.ServicePrincipalToken.
+func newServicePrincipalToken(token *adal.ServicePrincipalToken, err error) (contextToken, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return servicePrincipalToken{token}, nil
+}
+
+// ensureFresh implements contextToken.
+func (t servicePrincipalToken) ensureFresh(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return t.EnsureFresh()
+}
+
+// imdsTokenEndpoint is the managed identity token endpoint of the Azure
+// Instance Metadata Service, which is available on VMs, scale sets, and AKS
+// nodes.
+var imdsTokenEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// imdsAPIVersion is the version of the managed identity token API requested
+// from endpoints that are not given one.
+const imdsAPIVersion = "2018-02-01"
+
+// imdsProbeTimeout is how long imdsAvailable waits for the Instance Metadata
+// Service to respond.
+const imdsProbeTimeout = time.Second
+
+// imdsProbe holds the result of probing the Instance Metadata Service, which
+// is done at most once per process.
+var imdsProbe struct {
+	once      sync.Once
+	available bool
+}
+
+// imdsAvailable reports whether the Instance Metadata Service responds, which
+// indicates that az-dns is running in Azure. Any response will do; without the
+// Metadata header, it is an error.
+func imdsAvailable() bool {
+	imdsProbe.once.Do(func() {
+		client := &http.Client{Timeout: imdsProbeTimeout}
+		resp, err := client.Get(imdsTokenEndpoint)
+		if err != nil {
+			return
+		}
+		resp.Body.Close()
+		imdsProbe.available = true
+	})
+
+	return imdsProbe.available
+}
+
+// newMSIToken creates a token for a managed identity, requested from the
+// endpoint given by the msi-endpoint option or else from the Instance Metadata
+// Service. If the client-id option is set, it selects a user-assigned
+// identity; otherwise, the system-assigned identity is used.
+func newMSIToken(cloud *Cloud) (*msiToken, error) {
+	if err := requireCredentialOptions("subscription-id"); err != nil {
+		return nil, err
+	}
+
+	token := &msiToken{
+		endpoint: viper.GetString("msi-endpoint"),
+		resource: cloud.TokenAudience,
+		clientID: viper.GetString("client-id"),
+	}
+	if token.endpoint == "" {
+		token.endpoint = imdsTokenEndpoint
+	}
+	if _, err := url.Parse(token.endpoint); err != nil {
+		return nil, fmt.Errorf("invalid managed identity endpoint %q: %v", token.endpoint, err)
+	}
+
+	return token, nil
+}
+
+// newCLIToken creates a token for the account logged in to the Azure CLI. If
+// the subscription-id option is not set, a token is obtained at once to find
+// the CLI's current subscription.
+func newCLIToken(cloud *Cloud) (*cliToken, error) {
+	token := &cliToken{
+		resource:     cloud.TokenAudience,
+		subscription: viper.GetString("subscription-id"),
+	}
+
+	if token.subscription == "" {
+		if err := token.refresh(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+
+	return token, nil
+}
+
+// getChainedCredentials returns credentials that try each of the configured
+// authentication methods in turn, as listed by configuredAuthMethods, until
+// one obtains an access token, which is then used for every request. Methods
+// whose options are incomplete are skipped. With the verbose option, methods
+// that fail are logged to stderr.
+func getChainedCredentials(cloud *Cloud) (*clientCredentials, error) {
+	chain := &tokenChain{}
+	if viper.GetBool("verbose") {
+		chain.logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+
+	problems := []string{}
+	for _, method := range configuredAuthMethods() {
+		token, err := newAccessToken(cloud, method)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", method, err))
+			continue
+		}
+		chain.methods = append(chain.methods, method)
+		chain.tokens = append(chain.tokens, token)
+	}
+
+	switch len(chain.tokens) {
+	case 0:
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("no usable credentials provided:\n  %v", strings.Join(problems, "\n  "))
+		}
+		return nil, fmt.Errorf("no credentials provided; see \"az-dns --help\" for the supported authentication methods")
+	case 1:
+		return newTokenCredentials(cloud, chain.tokens[0]), nil
+	}
+
+	credentials := newTokenCredentials(cloud, chain)
+	for _, token := range chain.tokens {
+		// Only the Azure CLI works without the subscription-id option, so
+		// its subscription is used if it has one.
+		if cli, ok := token.(*cliToken); ok && credentials.subscriptionID == "" {
+			credentials.subscriptionID = cli.subscription
+		}
+	}
+
+	return credentials, nil
+}
+
+// tokenChain is an access token obtained with the first of several methods
+// that succeeds. Once one has, the others are not tried again.
+type tokenChain struct {
+	mu       sync.Mutex
+	methods  []string
+	tokens   []contextToken
+	selected contextToken
+	logf     func(format string, args ...interface{})
+}
+
+// OAuthToken implements adal.OAuthTokenProvider.
+func (c *tokenChain) OAuthToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.selected == nil {
+		return ""
+	}
+	return c.selected.OAuthToken()
+}
+
+// ensureFresh implements contextToken.
+func (c *tokenChain) ensureFresh(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.selected != nil {
+		return c.selected.ensureFresh(ctx)
+	}
+
+	problems := []string{}
+	for i, token := range c.tokens {
+		err := token.ensureFresh(ctx)
+		if err == nil {
+			c.selected = token
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if c.logf != nil {
+			c.logf("unable to authenticate with %v: %v", c.methods[i], err)
+		}
+		problems = append(problems, fmt.Sprintf("%v: %v", c.methods[i], err))
+	}
+
+	return fmt.Errorf("no authentication method succeeded:\n  %v", strings.Join(problems, "\n  "))
+}
+
+// tokenRefreshWithin is how long before it expires an access token obtained
+// from the Azure CLI or a managed identity endpoint is replaced.
+const tokenRefreshWithin = 5 * time.Minute
+
+// contextToken is an access token that is obtained with the context of the
+// request that needs it, so that it cannot outlast the command's timeout or
+// interruption.
+type contextToken interface {
+	adal.OAuthTokenProvider
+	// ensureFresh obtains a new access token if the current one is missing
+	// or about to expire.
+	ensureFresh(ctx context.Context) error
+}
+
+// tokenAuthorizer is an autorest.Authorizer that, like
+// autorest.BearerAuthorizer, sends a bearer token with each request, but
+// refreshes it with the context of the request.
+type tokenAuthorizer struct {
+	token contextToken
+}
+
+// WithAuthorization implements autorest.Authorizer.
+func (a *tokenAuthorizer) WithAuthorization() autorest.PrepareDecorator {
+	return func(p autorest.Preparer) autorest.Preparer {
+		return autorest.PreparerFunc(func(r *http.Request) (*http.Request, error) {
+			if err := a.token.ensureFresh(r.Context()); err != nil {
+				// The package type is that used by autorest.BearerAuthorizer,
+				// so that the error is described as an authentication failure.
+				return r, autorest.NewErrorWithError(err, "azure.BearerAuthorizer", "WithAuthorization", nil,
+					"Failed to refresh the Token for request to %s", r.URL)
+			}
+			return autorest.WithBearerAuthorization(a.token.OAuthToken())(p).Prepare(r)
+		})
+	}
+}
+
+// cliToken is an access token obtained by running the Azure CLI.
+type cliToken struct {
+	mu           sync.Mutex
+	resource     string
+	subscription string
+	accessToken  string
+	expiresOn    time.Time
+}
+
+// OAuthToken implements adal.OAuthTokenProvider.
+func (t *cliToken) OAuthToken() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.accessToken
+}
+
+// ensureFresh implements contextToken.
+func (t *cliToken) ensureFresh(ctx context.Context) error {
+	t.mu.Lock()
+	fresh := t.accessToken != "" && time.Until(t.expiresOn) > tokenRefreshWithin
+	t.mu.Unlock()
+
+	if fresh {
+		return nil
+	}
+	return t.refresh(ctx)
+}
+
+// refresh obtains a new access token from the Azure CLI, which is killed if
+// ctx ends first.
+func (t *cliToken) refresh(ctx context.Context) error {
+	args := []string{"account", "get-access-token", "--resource", t.resource, "--output", "json"}
+	if t.subscription != "" {
+		args = append(args, "--subscription", t.subscription)
+	}
+
+	var stderr bytes.Buffer
+	command := exec.CommandContext(ctx, azureCLICommand, args...)
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("unable to get an access token from the Azure CLI: %v", message)
+		}
+		return fmt.Errorf("unable to get an access token from the Azure CLI: %v", err)
+	}
+
+	token, err := parseCLIToken(output)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.accessToken = token.AccessToken
+	t.expiresOn = token.expires
+	if t.subscription == "" {
+		t.subscription = token.Subscription
+	}
+
+	return nil
+}
+
+// cliTokenOutput is the output of "az account get-access-token".
+type cliTokenOutput struct {
+	AccessToken    string `json:"accessToken"`
+	ExpiresOn      string `json:"expiresOn"`
+	ExpiresOnPOSIX int64  `json:"expires_on"`
+	Subscription   string `json:"subscription"`
+
+	expires time.Time
+}
+
+// parseCLIToken parses the output of "az account get-access-token". Older
+// versions of the Azure CLI only give the expiry time in the local time zone.
+func parseCLIToken(data []byte) (*cliTokenOutput, error) {
+	token := &cliTokenOutput{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, fmt.Errorf("unable to parse the Azure CLI access token: %v", err)
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("the Azure CLI did not return an access token")
+	}
+
+	if token.ExpiresOnPOSIX != 0 {
+		token.expires = time.Unix(token.ExpiresOnPOSIX, 0)
+		return token, nil
+	}
+
+	expires, err := time.ParseInLocation("2006-01-02 15:04:05.999999", token.ExpiresOn, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the Azure CLI access token expiry time: %v", err)
+	}
+	token.expires = expires
+
+	return token, nil
+}
+
+// msiToken is an access token for a managed identity, obtained with a GET
+// request to a token endpoint such as that of the Instance Metadata Service.
+type msiToken struct {
+	mu          sync.Mutex
+	endpoint    string
+	resource    string
+	clientID    string
+	accessToken string
+	expiresOn   time.Time
+}
+
+// msiHTTPClient is used to request managed identity tokens.
+var msiHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// OAuthToken implements adal.OAuthTokenProvider.
+func (t *msiToken) OAuthToken() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.accessToken
+}
+
+// ensureFresh implements contextToken.
+func (t *msiToken) ensureFresh(ctx context.Context) error {
+	t.mu.Lock()
+	fresh := t.accessToken != "" && time.Until(t.expiresOn) > tokenRefreshWithin
+	t.mu.Unlock()
+
+	if fresh {
+		return nil
+	}
+	return t.refresh(ctx)
+}
+
+// refresh requests a new access token from the token endpoint, giving up if
+// ctx ends first.
+func (t *msiToken) refresh(ctx context.Context) error {
+	u, err := url.Parse(t.endpoint)
+	if err != nil {
+		return err
+	}
+
+	query := u.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", imdsAPIVersion)
+	}
+	query.Set("resource", t.resource)
+	if t.clientID != "" {
+		query.Set("client_id", t.clientID)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := msiHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("unable to get a managed identity access token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to get a managed identity access token: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get a managed identity access token: %v: %v", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	token, err := parseMSIToken(body)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.accessToken = token.AccessToken
+	t.expiresOn = token.expires
+
+	return nil
+}
+
+// msiTokenOutput is the response of a managed identity token endpoint.
+type msiTokenOutput struct {
+	AccessToken string          `json:"access_token"`
+	ExpiresOn   json.RawMessage `json:"expires_on"`
+
+	expires time.Time
+}
+
+// parseMSIToken parses the response of a managed identity token endpoint. The
+// expiry time is in seconds since the epoch, which the Instance Metadata
+// Service gives as a string.
+func parseMSIToken(data []byte) (*msiTokenOutput, error) {
+	token := &msiTokenOutput{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, fmt.Errorf("unable to parse the managed identity access token: %v", err)
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("the managed identity endpoint did not return an access token")
+	}
+
+	expiresOn, err := strconv.ParseInt(strings.Trim(string(token.ExpiresOn), `"`), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the managed identity access token expiry time: %v", err)
+	}
+	token.expires = time.Unix(expiresOn, 0)
+
+	return token, nil
+}
@@ -0,0 +1,416 @@
+package helpers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func generateTestCertificate(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "az-dns"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestParseCertificate(t *testing.T) {
+	key, certificate := generateTestCertificate(t)
+	otherKey, _ := generateTestCertificate(t)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	pkcs8DER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8DER})
+	encryptedBlock, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte("hunter2"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := pem.EncodeToMemory(encryptedBlock)
+	mismatched := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(otherKey)})
+
+	join := func(blocks ...[]byte) []byte {
+		data := []byte{}
+		for _, block := range blocks {
+			data = append(data, block...)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password string
+		valid    bool
+	}{
+		{"PKCS #1 key", join(certificate, pkcs1), "", true},
+		{"PKCS #8 key", join(certificate, pkcs8), "", true},
+		{"key first", join(pkcs1, certificate), "", true},
+		{"encrypted key", join(certificate, encrypted), "hunter2", true},
+		{"encrypted key without password", join(certificate, encrypted), "", false},
+		{"encrypted key with wrong password", join(certificate, encrypted), "hunter3", false},
+		{"no key", certificate, "", false},
+		{"no certificate", pkcs1, "", false},
+		{"mismatched key", join(certificate, mismatched), "", false},
+		{"not PEM", []byte("certificate"), "", false},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			parsedCertificate, parsedKey, err := ParseCertificate(testCase.data, testCase.password)
+			if !testCase.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, "az-dns", parsedCertificate.Subject.CommonName)
+				assert.Equal(t, key.N, parsedKey.N)
+			}
+		})
+	}
+}
+
+func TestParseCLIToken(t *testing.T) {
+	token, err := parseCLIToken([]byte(`{
+  "accessToken": "token",
+  "expiresOn": "2019-05-08 15:09:27.000000",
+  "expires_on": 1557328167,
+  "subscription": "00000000-0000-0000-0000-000000000000",
+  "tenant": "11111111-1111-1111-1111-111111111111",
+  "tokenType": "Bearer"
+}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "token", token.AccessToken)
+		assert.Equal(t, "00000000-0000-0000-0000-000000000000", token.Subscription)
+		assert.Equal(t, int64(1557328167), token.expires.Unix())
+	}
+
+	token, err = parseCLIToken([]byte(`{"accessToken": "token", "expiresOn": "2019-05-08 15:09:27.123456"}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Date(2019, 5, 8, 15, 9, 27, 123456000, time.Local), token.expires)
+	}
+
+	_, err = parseCLIToken([]byte(`{"expiresOn": "2019-05-08 15:09:27.000000"}`))
+	assert.Error(t, err)
+
+	_, err = parseCLIToken([]byte(`{"accessToken": "token", "expiresOn": "tomorrow"}`))
+	assert.Error(t, err)
+
+	_, err = parseCLIToken([]byte(`ERROR: Please run 'az login' to setup account.`))
+	assert.Error(t, err)
+}
+
+func TestCLIToken(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "az-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "az")
+	err = ioutil.WriteFile(script, []byte(`#!/bin/sh
+echo "{\"accessToken\": \"token-$4\", \"expires_on\": $(( $(date +%s) + 3600 )), \"subscription\": \"subscription\"}"
+`), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(command string) { azureCLICommand = command }(azureCLICommand)
+	azureCLICommand = script
+
+	ctx := context.Background()
+	token := &cliToken{resource: "https://management.azure.com/"}
+	assert.NoError(t, token.ensureFresh(ctx))
+	assert.Equal(t, "token-https://management.azure.com/", token.OAuthToken())
+	assert.Equal(t, "subscription", token.subscription)
+
+	azureCLICommand = filepath.Join(dir, "missing")
+	assert.NoError(t, token.ensureFresh(ctx))
+	assert.Error(t, token.refresh(ctx))
+}
+
+func TestFederatedTokenSecret(t *testing.T) {
+	file, err := ioutil.TempFile("", "az-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	file.WriteString("assertion\n")
+	file.Close()
+
+	secret := &federatedTokenSecret{path: file.Name()}
+	values := url.Values{}
+	assert.NoError(t, secret.SetAuthenticationValues(nil, &values))
+	assert.Equal(t, "assertion", values.Get("client_assertion"))
+	assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", values.Get("client_assertion_type"))
+
+	secret.path = file.Name() + ".missing"
+	assert.Error(t, secret.SetAuthenticationValues(nil, &values))
+}
+
+type configuredAuthMethodsTestCase struct {
+	name     string
+	options  map[string]string
+	imds     bool
+	cli      bool
+	expected []string
+}
+
+var configuredAuthMethodsTests = []configuredAuthMethodsTestCase{
+	{"client secret", map[string]string{"client-secret": "secret", "client-certificate": "cert.pem"}, true, true,
+		[]string{AuthMethodClientSecret, AuthMethodClientCertificate, AuthMethodMSI, AuthMethodCLI}},
+	{"client certificate", map[string]string{"client-certificate": "cert.pem", "federated-token-file": "token"}, false, false,
+		[]string{AuthMethodClientCertificate, AuthMethodFederatedToken}},
+	{"federated token", map[string]string{"federated-token-file": "token", "msi-endpoint": "http://localhost:50342/oauth2/token"}, false, true,
+		[]string{AuthMethodFederatedToken, AuthMethodMSI, AuthMethodCLI}},
+	{"managed identity endpoint", map[string]string{"client-id": "id", "msi-endpoint": "http://localhost:50342/oauth2/token"}, false, false,
+		[]string{AuthMethodMSI}},
+	{"instance metadata service", map[string]string{"client-id": "id"}, true, true, []string{AuthMethodMSI, AuthMethodCLI}},
+	{"Azure CLI", map[string]string{"client-id": "id"}, false, true, []string{AuthMethodCLI}},
+	{"nothing", map[string]string{"client-id": "id"}, false, false, []string{}},
+}
+
+func TestConfiguredAuthMethods(t *testing.T) {
+	for _, testCase := range configuredAuthMethodsTests {
+		t.Run(testCase.name, func(t *testing.T) { testConfiguredAuthMethods(t, testCase) })
+	}
+}
+
+func testConfiguredAuthMethods(t *testing.T, testCase configuredAuthMethodsTestCase) {
+	defer viper.Reset()
+	for key, value := range testCase.options {
+		viper.Set(key, value)
+	}
+
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Required metadata header not specified", http.StatusBadRequest)
+	}))
+	defer imds.Close()
+
+	defer func(endpoint string) { imdsTokenEndpoint = endpoint }(imdsTokenEndpoint)
+	imdsTokenEndpoint = imds.URL + "/metadata/identity/oauth2/token"
+	if !testCase.imds {
+		imds.Close()
+	}
+	imdsProbe.once, imdsProbe.available = sync.Once{}, false
+
+	defer func(command string) { azureCLICommand = command }(azureCLICommand)
+	azureCLICommand = os.Args[0]
+	if !testCase.cli {
+		azureCLICommand = os.Args[0] + ".missing"
+	}
+
+	assert.Equal(t, testCase.expected, configuredAuthMethods())
+}
+
+func TestIMDSProbedOnce(t *testing.T) {
+	probes := 0
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		http.Error(w, "Required metadata header not specified", http.StatusBadRequest)
+	}))
+	defer imds.Close()
+
+	defer func(endpoint string) { imdsTokenEndpoint = endpoint }(imdsTokenEndpoint)
+	imdsTokenEndpoint = imds.URL + "/metadata/identity/oauth2/token"
+	imdsProbe.once, imdsProbe.available = sync.Once{}, false
+
+	assert.True(t, imdsAvailable())
+	assert.True(t, imdsAvailable())
+	assert.Equal(t, 1, probes)
+}
+
+// fakeToken is a contextToken that fails to refresh if err is set.
+type fakeToken struct {
+	token     string
+	err       error
+	refreshes int
+}
+
+func (t *fakeToken) OAuthToken() string {
+	return t.token
+}
+
+func (t *fakeToken) ensureFresh(ctx context.Context) error {
+	t.refreshes++
+	return t.err
+}
+
+func TestTokenChain(t *testing.T) {
+	ctx := context.Background()
+	msi := &fakeToken{token: "msi", err: errors.New("identity not found")}
+	cli := &fakeToken{token: "cli"}
+	chain := &tokenChain{methods: []string{AuthMethodMSI, AuthMethodCLI}, tokens: []contextToken{msi, cli}}
+
+	assert.Equal(t, "", chain.OAuthToken())
+	assert.NoError(t, chain.ensureFresh(ctx))
+	assert.Equal(t, "cli", chain.OAuthToken())
+
+	// Once a method has succeeded, only it is used.
+	assert.NoError(t, chain.ensureFresh(ctx))
+	assert.Equal(t, 1, msi.refreshes)
+	assert.Equal(t, 2, cli.refreshes)
+
+	cli.err = errors.New("not logged in")
+	chain = &tokenChain{methods: []string{AuthMethodMSI, AuthMethodCLI}, tokens: []contextToken{msi, cli}}
+	err := chain.ensureFresh(ctx)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "msi: identity not found")
+		assert.Contains(t, err.Error(), "cli: not logged in")
+	}
+}
+
+func TestMSIToken(t *testing.T) {
+	requests := []url.Values{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/metadata/identity/oauth2/token" || r.Header.Get("Metadata") != "true" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		requests = append(requests, query)
+		if query.Get("resource") == "https://forbidden.example.com/" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprintf(w, `{"access_token": "token-%v", "expires_on": "%v", "token_type": "Bearer"}`,
+			query.Get("client_id"), time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	token := &msiToken{
+		endpoint: server.URL + "/metadata/identity/oauth2/token",
+		resource: "https://management.azure.com/",
+		clientID: "id",
+	}
+	assert.NoError(t, token.ensureFresh(ctx))
+	assert.Equal(t, "token-id", token.OAuthToken())
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "2018-02-01", requests[0].Get("api-version"))
+		assert.Equal(t, "https://management.azure.com/", requests[0].Get("resource"))
+		assert.Equal(t, "id", requests[0].Get("client_id"))
+	}
+
+	// A fresh token is not requested again.
+	assert.NoError(t, token.ensureFresh(ctx))
+	assert.Len(t, requests, 1)
+
+	// An API version in the endpoint is kept, and the system-assigned
+	// identity is used without a client ID.
+	token = &msiToken{
+		endpoint: server.URL + "/metadata/identity/oauth2/token?api-version=2019-08-01",
+		resource: "https://management.azure.com/",
+	}
+	assert.NoError(t, token.refresh(ctx))
+	assert.Equal(t, "token-", token.OAuthToken())
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "2019-08-01", requests[1].Get("api-version"))
+		_, ok := requests[1]["client_id"]
+		assert.False(t, ok)
+	}
+
+	token.resource = "https://forbidden.example.com/"
+	assert.Error(t, token.refresh(ctx))
+}
+
+func TestMSITokenCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	token := &msiToken{endpoint: server.URL, resource: "https://management.azure.com/"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Error(t, token.ensureFresh(ctx))
+	assert.True(t, time.Since(start) < 5*time.Second, "the request was not canceled")
+}
+
+func TestTokenAuthorizer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token": "token", "expires_on": "%v"}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	authorizer := &tokenAuthorizer{token: &msiToken{endpoint: server.URL + "/token", resource: "https://management.azure.com/"}}
+	req, err := autorest.Prepare((&http.Request{}).WithContext(context.Background()),
+		autorest.WithBaseURL(server.URL), authorizer.WithAuthorization())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	}
+
+	authorizer = &tokenAuthorizer{token: &msiToken{endpoint: server.URL + "/missing", resource: "https://management.azure.com/"}}
+	_, err = autorest.Prepare((&http.Request{}).WithContext(context.Background()),
+		autorest.WithBaseURL(server.URL), authorizer.WithAuthorization())
+	assert.True(t, IsAuthFailure(err))
+}
+
+func TestParseMSIToken(t *testing.T) {
+	token, err := parseMSIToken([]byte(`{"access_token": "token", "expires_on": "1506484173"}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "token", token.AccessToken)
+		assert.Equal(t, time.Unix(1506484173, 0), token.expires)
+	}
+
+	token, err = parseMSIToken([]byte(`{"access_token": "token", "expires_on": 1506484173}`))
+	if assert.NoError(t, err) {
+		assert.Equal(t, time.Unix(1506484173, 0), token.expires)
+	}
+
+	_, err = parseMSIToken([]byte(`{"expires_on": "1506484173"}`))
+	assert.Error(t, err)
+
+	_, err = parseMSIToken([]byte(`{"access_token": "token", "expires_on": "tomorrow"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,274 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// ChangeAction describes what must be done to a record set to bring it to its
+// desired state.
+type ChangeAction string
+
+const (
+	// ChangeCreate indicates that a record set must be created.
+	ChangeCreate ChangeAction = "create"
+	// ChangeUpdate indicates that an existing record set must be replaced.
+	ChangeUpdate ChangeAction = "update"
+	// ChangeDelete indicates that an existing record set must be deleted.
+	ChangeDelete ChangeAction = "delete"
+)
+
+// RecordSetChange is a single difference between the current and desired
+// states of a zone.
+type RecordSetChange struct {
+	Action ChangeAction
+	Name   string
+	Type   dns.RecordType
+	// Current is the record set as it exists in the zone, or nil if it must be
+	// created.
+	Current *dns.RecordSet
+	// Desired is the record set as it should exist, or nil if it must be
+	// deleted.
+	Desired *dns.RecordSet
+}
+
+// DiffRecordSets compares the record sets currently in a zone with the desired
+// record sets and returns the changes required to reconcile them, sorted by
+// name and type. Record sets are matched by name, case-insensitively, and type.
+// A desired record set differs from the current one if its TTL or records
+// differ, or if its metadata differs and the desired record set specifies
+// metadata at all. Record sets that exist but are not desired are deleted only
+// if prune is true, and the SOA and apex NS record sets, which are managed by
+// Azure DNS, are never deleted.
+func DiffRecordSets(current []dns.RecordSet, desired []dns.RecordSet, prune bool) []RecordSetChange {
+	type recordSetKey struct {
+		name       string
+		recordType dns.RecordType
+	}
+
+	keyOf := func(rrset dns.RecordSet) recordSetKey {
+		return recordSetKey{strings.ToLower(to.String(rrset.Name)), RecordSetType(rrset)}
+	}
+
+	existing := map[recordSetKey]dns.RecordSet{}
+	for _, rrset := range current {
+		existing[keyOf(rrset)] = rrset
+	}
+
+	changes := []RecordSetChange{}
+	wanted := map[recordSetKey]bool{}
+
+	for i := range desired {
+		rrset := desired[i]
+		key := keyOf(rrset)
+		wanted[key] = true
+
+		change := RecordSetChange{
+			Name:    to.String(rrset.Name),
+			Type:    key.recordType,
+			Desired: &rrset,
+		}
+
+		if live, ok := existing[key]; !ok {
+			change.Action = ChangeCreate
+		} else if !RecordSetsEqual(live, rrset) {
+			change.Action = ChangeUpdate
+			change.Name = to.String(live.Name)
+			change.Current = &live
+		} else {
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	if prune {
+		for i := range current {
+			rrset := current[i]
+			key := keyOf(rrset)
+			if wanted[key] || key.recordType == dns.SOA || (key.recordType == dns.NS && key.name == "@") {
+				continue
+			}
+
+			changes = append(changes, RecordSetChange{
+				Action:  ChangeDelete,
+				Name:    to.String(rrset.Name),
+				Type:    key.recordType,
+				Current: &rrset,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if c := CompareRecordNames(changes[i].Name, changes[j].Name); c != 0 {
+			return c < 0
+		}
+		return recordTypeOrder(changes[i].Type) < recordTypeOrder(changes[j].Type)
+	})
+
+	return changes
+}
+
+// RecordSetsEqual reports whether the desired record set is already satisfied
+// by the current one. The records are compared without regard to order, and
+// metadata is only compared if the desired record set has any.
+func RecordSetsEqual(current dns.RecordSet, desired dns.RecordSet) bool {
+	recordType := RecordSetType(desired)
+	if RecordSetType(current) != recordType {
+		return false
+	}
+
+	currentProps := current.RecordSetProperties
+	desiredProps := desired.RecordSetProperties
+	if currentProps == nil || desiredProps == nil {
+		return currentProps == desiredProps
+	}
+
+	if to.Int64(currentProps.TTL) != to.Int64(desiredProps.TTL) {
+		return false
+	}
+
+	if desiredProps.Metadata != nil {
+		currentMetadata := map[string]string{}
+		if currentProps.Metadata != nil {
+			currentMetadata = to.StringMap(*currentProps.Metadata)
+		}
+		if !reflect.DeepEqual(currentMetadata, to.StringMap(*desiredProps.Metadata)) {
+			return false
+		}
+	}
+
+	currentValues := normalizedRecordValues(recordType, currentProps)
+	desiredValues := normalizedRecordValues(recordType, desiredProps)
+
+	return reflect.DeepEqual(currentValues, desiredValues)
+}
+
+// normalizedRecordValues returns the sorted keys of the records in props, so
+// that equivalent record sets can be compared regardless of record order.
+func normalizedRecordValues(recordType dns.RecordType, props *dns.RecordSetProperties) []string {
+	values := recordKeys(recordType, props)
+	sort.Strings(values)
+	return values
+}
+
+// recordKeys returns a key for each record in props, in order, such that two
+// records have the same key if they are equivalent. Keys are the zone file
+// representation of the records, lowercased except for character strings,
+// which are case-sensitive.
+func recordKeys(recordType dns.RecordType, props *dns.RecordSetProperties) []string {
+	values := ZoneFileValues(recordType, props)
+	if recordType != dns.TXT && recordType != dns.CAA {
+		for i, value := range values {
+			values[i] = strings.ToLower(value)
+		}
+	}
+
+	return values
+}
+
+// RecordSetDiff returns the difference between the current and desired states
+// of the record set called name in zone as a unified diff, or the empty string
+// if there is none. Either state may be nil if the record set does not exist.
+// Each record is a line in zone file presentation format, preceded by a comment
+// line for each metadata entry, and lines are sorted so that the diff does not
+// depend on the order of records. Unlike RecordSetsEqual, this compares
+// metadata even when the desired record set has none, as writing it would
+// remove any that exist.
+func RecordSetDiff(zone string, name string, recordType dns.RecordType, current *dns.RecordSet, desired *dns.RecordSet) string {
+	zone = strings.TrimRight(zone, ".")
+	label := GenerateRecordName(name, zone, true)
+	fqdn := Fqdn(zone)
+	if label != "@" {
+		fqdn = label + "." + fqdn
+	}
+
+	currentLines := presentationLines(label, recordType, current)
+	desiredLines := presentationLines(label, recordType, desired)
+	if reflect.DeepEqual(currentLines, desiredLines) {
+		return ""
+	}
+
+	header := func(prefix string, rrset *dns.RecordSet, state string) string {
+		if rrset == nil {
+			return prefix + " /dev/null\n"
+		}
+		return fmt.Sprintf("%v %v %v (%v)\n", prefix, fqdn, recordType, state)
+	}
+
+	var b strings.Builder
+	b.WriteString(header("---", current, "current"))
+	b.WriteString(header("+++", desired, "desired"))
+	fmt.Fprintf(&b, "@@ -%v +%v @@\n", hunkRange(len(currentLines)), hunkRange(len(desiredLines)))
+
+	// Lines removed and added between two unchanged lines are written as a
+	// group, removals first, as diff does.
+	removed, added := []string{}, []string{}
+	flush := func() {
+		for _, line := range removed {
+			b.WriteString("-" + line + "\n")
+		}
+		for _, line := range added {
+			b.WriteString("+" + line + "\n")
+		}
+		removed, added = removed[:0], added[:0]
+	}
+
+	i, j := 0, 0
+	for i < len(currentLines) || j < len(desiredLines) {
+		switch {
+		case j == len(desiredLines) || (i < len(currentLines) && currentLines[i] < desiredLines[j]):
+			removed = append(removed, currentLines[i])
+			i++
+		case i == len(currentLines) || desiredLines[j] < currentLines[i]:
+			added = append(added, desiredLines[j])
+			j++
+		default:
+			flush()
+			b.WriteString(" " + currentLines[i] + "\n")
+			i++
+			j++
+		}
+	}
+	flush()
+
+	return b.String()
+}
+
+// presentationLines returns the sorted lines that RecordSetDiff uses to
+// represent rrset, which are empty if rrset is nil.
+func presentationLines(label string, recordType dns.RecordType, rrset *dns.RecordSet) []string {
+	lines := []string{}
+	if rrset == nil || rrset.RecordSetProperties == nil {
+		return lines
+	}
+
+	if rrset.Metadata != nil {
+		for key, value := range to.StringMap(*rrset.Metadata) {
+			lines = append(lines, fmt.Sprintf("; metadata %v=%v", key, value))
+		}
+	}
+
+	ttl := to.Int64(rrset.TTL)
+	for _, value := range ZoneFileValues(recordType, rrset.RecordSetProperties) {
+		lines = append(lines, fmt.Sprintf("%v\t%v\tIN\t%v\t%v", label, ttl, recordType, value))
+	}
+
+	sort.Strings(lines)
+	return lines
+}
+
+// hunkRange formats the range of lines covered by a unified diff hunk that
+// spans a whole file of the given length.
+func hunkRange(length int) string {
+	if length == 0 {
+		return "0,0"
+	}
+
+	return fmt.Sprintf("1,%v", length)
+}
@@ -0,0 +1,201 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestARecordSet(name string, ttl int64, addresses ...string) dns.RecordSet {
+	records := []dns.ARecord{}
+	for _, address := range addresses {
+		records = append(records, dns.ARecord{Ipv4Address: to.StringPtr(address)})
+	}
+
+	return newTestRecordSet(name, dns.A, ttl, dns.RecordSetProperties{ARecords: &records})
+}
+
+func TestDiffRecordSets(t *testing.T) {
+	current := []dns.RecordSet{
+		newTestRecordSet("@", dns.SOA, 3600, dns.RecordSetProperties{SoaRecord: &dns.SoaRecord{}}),
+		newTestRecordSet("@", dns.NS, 172800, dns.RecordSetProperties{
+			NsRecords: &[]dns.NsRecord{{Nsdname: to.StringPtr("ns1-01.azure-dns.com.")}},
+		}),
+		newTestARecordSet("same", 300, "192.0.2.1", "192.0.2.2"),
+		newTestARecordSet("values", 300, "192.0.2.1"),
+		newTestARecordSet("ttl", 300, "192.0.2.1"),
+		newTestARecordSet("unmanaged", 300, "192.0.2.1"),
+		newTestRecordSet("mx", dns.MX, 300, dns.RecordSetProperties{
+			MxRecords: &[]dns.MxRecord{{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("mail.example.com")}},
+		}),
+	}
+
+	desired := []dns.RecordSet{
+		newTestARecordSet("SAME", 300, "192.0.2.2", "192.0.2.1"),
+		newTestARecordSet("values", 300, "192.0.2.3"),
+		newTestARecordSet("ttl", 600, "192.0.2.1"),
+		newTestARecordSet("new", 300, "192.0.2.1"),
+		newTestRecordSet("mx", dns.MX, 300, dns.RecordSetProperties{
+			MxRecords: &[]dns.MxRecord{{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("MAIL.example.com.")}},
+		}),
+	}
+
+	changes := DiffRecordSets(current, desired, false)
+	summary := []string{}
+	for _, change := range changes {
+		summary = append(summary, string(change.Action)+" "+change.Name+" "+string(change.Type))
+	}
+	assert.Equal(t, []string{"create new A", "update ttl A", "update values A"}, summary)
+
+	changes = DiffRecordSets(current, desired, true)
+	summary = []string{}
+	for _, change := range changes {
+		summary = append(summary, string(change.Action)+" "+change.Name+" "+string(change.Type))
+	}
+	assert.Equal(t, []string{"create new A", "update ttl A", "delete unmanaged A", "update values A"}, summary)
+
+	for _, change := range changes {
+		switch change.Action {
+		case ChangeCreate:
+			assert.Nil(t, change.Current)
+			assert.NotNil(t, change.Desired)
+		case ChangeUpdate:
+			assert.NotNil(t, change.Current)
+			assert.NotNil(t, change.Desired)
+		case ChangeDelete:
+			assert.NotNil(t, change.Current)
+			assert.Nil(t, change.Desired)
+		}
+	}
+}
+
+func TestRecordSetsEqualMetadata(t *testing.T) {
+	current := newTestARecordSet("www", 300, "192.0.2.1")
+	current.Metadata = &map[string]*string{"owner": to.StringPtr("web")}
+
+	desired := newTestARecordSet("www", 300, "192.0.2.1")
+	assert.True(t, RecordSetsEqual(current, desired), "metadata should be ignored when not desired")
+
+	desired.Metadata = &map[string]*string{"owner": to.StringPtr("web")}
+	assert.True(t, RecordSetsEqual(current, desired))
+
+	desired.Metadata = &map[string]*string{"owner": to.StringPtr("mail")}
+	assert.False(t, RecordSetsEqual(current, desired))
+
+	desired.Metadata = &map[string]*string{}
+	assert.False(t, RecordSetsEqual(current, desired))
+}
+
+func TestRecordSetsEqualTXTCase(t *testing.T) {
+	current := newTestRecordSet("txt", dns.TXT, 300, dns.RecordSetProperties{
+		TxtRecords: &[]dns.TxtRecord{{Value: &[]string{"Value"}}},
+	})
+	desired := newTestRecordSet("txt", dns.TXT, 300, dns.RecordSetProperties{
+		TxtRecords: &[]dns.TxtRecord{{Value: &[]string{"value"}}},
+	})
+
+	assert.False(t, RecordSetsEqual(current, desired))
+}
+
+type recordSetDiffTestCase struct {
+	name     string
+	current  *dns.RecordSet
+	desired  *dns.RecordSet
+	expected string
+}
+
+func TestRecordSetDiff(t *testing.T) {
+	www := newTestARecordSet("www", 300, "192.0.2.2", "192.0.2.1")
+	reordered := newTestARecordSet("www", 300, "192.0.2.1", "192.0.2.2")
+	changed := newTestARecordSet("www", 300, "192.0.2.1", "192.0.2.3")
+	longer := newTestARecordSet("www", 600, "192.0.2.1")
+	shorter := newTestARecordSet("www", 120, "192.0.2.1", "192.0.2.2")
+	tagged := newTestARecordSet("www", 300, "192.0.2.1", "192.0.2.2")
+	tagged.Metadata = &map[string]*string{"owner": to.StringPtr("web")}
+
+	testCases := []recordSetDiffTestCase{
+		{
+			name:     "unchanged",
+			current:  &www,
+			desired:  &reordered,
+			expected: "",
+		},
+		{
+			name:    "records",
+			current: &www,
+			desired: &changed,
+			expected: "--- www.example.com. A (current)\n" +
+				"+++ www.example.com. A (desired)\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				" www\t300\tIN\tA\t192.0.2.1\n" +
+				"-www\t300\tIN\tA\t192.0.2.2\n" +
+				"+www\t300\tIN\tA\t192.0.2.3\n",
+		},
+		{
+			name:    "ttl",
+			current: &www,
+			desired: &longer,
+			expected: "--- www.example.com. A (current)\n" +
+				"+++ www.example.com. A (desired)\n" +
+				"@@ -1,2 +1,1 @@\n" +
+				"-www\t300\tIN\tA\t192.0.2.1\n" +
+				"-www\t300\tIN\tA\t192.0.2.2\n" +
+				"+www\t600\tIN\tA\t192.0.2.1\n",
+		},
+		{
+			name:    "removals first",
+			current: &tagged,
+			desired: &shorter,
+			expected: "--- www.example.com. A (current)\n" +
+				"+++ www.example.com. A (desired)\n" +
+				"@@ -1,3 +1,2 @@\n" +
+				"-; metadata owner=web\n" +
+				"-www\t300\tIN\tA\t192.0.2.1\n" +
+				"-www\t300\tIN\tA\t192.0.2.2\n" +
+				"+www\t120\tIN\tA\t192.0.2.1\n" +
+				"+www\t120\tIN\tA\t192.0.2.2\n",
+		},
+		{
+			name:    "metadata removed",
+			current: &tagged,
+			desired: &www,
+			expected: "--- www.example.com. A (current)\n" +
+				"+++ www.example.com. A (desired)\n" +
+				"@@ -1,3 +1,2 @@\n" +
+				"-; metadata owner=web\n" +
+				" www\t300\tIN\tA\t192.0.2.1\n" +
+				" www\t300\tIN\tA\t192.0.2.2\n",
+		},
+		{
+			name:    "create",
+			desired: &longer,
+			expected: "--- /dev/null\n" +
+				"+++ www.example.com. A (desired)\n" +
+				"@@ -0,0 +1,1 @@\n" +
+				"+www\t600\tIN\tA\t192.0.2.1\n",
+		},
+		{
+			name:    "delete",
+			current: &longer,
+			expected: "--- www.example.com. A (current)\n" +
+				"+++ /dev/null\n" +
+				"@@ -1,1 +0,0 @@\n" +
+				"-www\t600\tIN\tA\t192.0.2.1\n",
+		},
+		{
+			name:     "nothing to delete",
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) { testRecordSetDiff(t, testCase) })
+	}
+}
+
+func testRecordSetDiff(t *testing.T, testCase recordSetDiffTestCase) {
+	diff := RecordSetDiff("example.com", "www", dns.A, testCase.current, testCase.desired)
+	assert.Equal(t, testCase.expected, diff)
+}
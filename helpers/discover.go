@@ -0,0 +1,191 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// ZoneLocation identifies a DNS zone and the resource group containing it.
+type ZoneLocation struct {
+	Name          string `json:"name"`
+	ResourceGroup string `json:"resourceGroup"`
+}
+
+// ZoneLocations returns the locations of zones, taking the resource group of
+// each from its resource ID.
+func ZoneLocations(zones []dns.Zone) []ZoneLocation {
+	locations := []ZoneLocation{}
+	for _, zone := range zones {
+		locations = append(locations, ZoneLocation{
+			Name:          to.String(zone.Name),
+			ResourceGroup: ResourceGroupFromID(to.String(zone.ID)),
+		})
+	}
+
+	return locations
+}
+
+// FindZone returns the zone that contains fqdn: the one whose name is the
+// longest suffix of fqdn, matching whole labels. Names are compared without
+// regard to case or trailing dots. If no zone contains fqdn, false is
+// returned.
+func FindZone(locations []ZoneLocation, fqdn string) (ZoneLocation, bool) {
+	fqdn = strings.ToLower(strings.TrimRight(fqdn, "."))
+
+	var found ZoneLocation
+	longest := 0
+	for _, location := range locations {
+		name := strings.ToLower(strings.TrimRight(location.Name, "."))
+		if name == "" || len(name) <= longest {
+			continue
+		}
+
+		if fqdn == name || strings.HasSuffix(fqdn, "."+name) {
+			found, longest = location, len(name)
+		}
+	}
+
+	return found, longest > 0
+}
+
+// ZoneNotFoundError indicates that a DNS zone could not be found.
+type ZoneNotFoundError struct {
+	// Name is the name of the zone, or of the host that should be in it.
+	Name string
+	// Containing indicates that no zone containing Name was found, rather than
+	// one named Name.
+	Containing bool
+}
+
+func (e *ZoneNotFoundError) Error() string {
+	if e.Containing {
+		return fmt.Sprintf("no DNS zone containing %v was found", e.Name)
+	}
+
+	return fmt.Sprintf("the DNS zone %v was not found", e.Name)
+}
+
+// ZoneCache stores the zones found in a subscription in a file, so that
+// separate invocations, such as ACME hooks, do not each need to list them.
+type ZoneCache struct {
+	// Path is the path to the cache file.
+	Path string
+	// MaxAge is how long cached zones are used before they are listed again.
+	// The cache is not used if it is zero.
+	MaxAge time.Duration
+}
+
+// zoneCacheEntry is the set of zones cached for a subscription.
+type zoneCacheEntry struct {
+	Updated time.Time      `json:"updated"`
+	Zones   []ZoneLocation `json:"zones"`
+}
+
+// read returns the cached zones for key, or false if there are none or they
+// are too old.
+func (cache ZoneCache) read(key string) ([]ZoneLocation, bool) {
+	if cache.MaxAge <= 0 {
+		return nil, false
+	}
+
+	entries := cache.load()
+	entry, ok := entries[key]
+	if !ok || time.Since(entry.Updated) > cache.MaxAge {
+		return nil, false
+	}
+
+	return entry.Zones, true
+}
+
+// write caches zones for key.
+func (cache ZoneCache) write(key string, zones []ZoneLocation) error {
+	if cache.MaxAge <= 0 {
+		return nil
+	}
+
+	entries := cache.load()
+	entries[key] = zoneCacheEntry{Updated: time.Now(), Zones: zones}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cache.Path), 0700); err != nil {
+		return err
+	}
+
+	// Write the new cache alongside the old one and rename it into place, so
+	// that concurrent readers never see a partial file.
+	temp, err := ioutil.TempFile(filepath.Dir(cache.Path), filepath.Base(cache.Path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), cache.Path)
+}
+
+// load returns the contents of the cache file, which are empty if it cannot be
+// read.
+func (cache ZoneCache) load() map[string]zoneCacheEntry {
+	entries := map[string]zoneCacheEntry{}
+
+	data, err := ioutil.ReadFile(cache.Path)
+	if err != nil {
+		return entries
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return map[string]zoneCacheEntry{}
+	}
+
+	return entries
+}
+
+// DiscoverZone finds the zone containing fqdn among those in resourceGroup,
+// or in the entire subscription if resourceGroup is empty. Zones are taken
+// from the cache if possible; they are listed again if the cache is stale or
+// none of the cached zones contains fqdn.
+func DiscoverZone(ctx context.Context, client *dns.ZonesClient, resourceGroup string, fqdn string, cache ZoneCache) (ZoneLocation, error) {
+	key := strings.Join([]string{client.BaseURI, client.SubscriptionID, strings.ToLower(resourceGroup)}, "|")
+
+	if locations, ok := cache.read(key); ok {
+		if location, ok := FindZone(locations, fqdn); ok {
+			return location, nil
+		}
+	}
+
+	zones, err := ListZones(ctx, client, resourceGroup)
+	if err != nil {
+		return ZoneLocation{}, err
+	}
+
+	locations := ZoneLocations(zones)
+	// The cache only saves time, so failing to write it is not an error.
+	_ = cache.write(key, locations)
+
+	location, ok := FindZone(locations, fqdn)
+	if !ok {
+		return ZoneLocation{}, &ZoneNotFoundError{Name: strings.TrimRight(fqdn, "."), Containing: true}
+	}
+
+	return location, nil
+}
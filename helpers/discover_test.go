@@ -0,0 +1,127 @@
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+var testZoneLocations = []ZoneLocation{
+	{"example.com", "dns"},
+	{"sub.example.com.", "dns-sub"},
+	{"ample.com", "dns-ample"},
+	{"example.org", "dns-org"},
+}
+
+type findZoneTestCase struct {
+	fqdn          string
+	resourceGroup string
+}
+
+var findZoneTests = []findZoneTestCase{
+	{"www.example.com", "dns"},
+	{"example.com", "dns"},
+	{"_acme-challenge.www.sub.example.com.", "dns-sub"},
+	{"SUB.Example.COM", "dns-sub"},
+	{"www.notsub.example.com", "dns"},
+	{"www.example.net", ""},
+	{"com", ""},
+}
+
+func TestFindZone(t *testing.T) {
+	for _, testCase := range findZoneTests {
+		t.Run(testCase.fqdn, func(t *testing.T) { testFindZone(t, testCase) })
+	}
+}
+
+func testFindZone(t *testing.T, testCase findZoneTestCase) {
+	location, ok := FindZone(testZoneLocations, testCase.fqdn)
+	assert.Equal(t, testCase.resourceGroup != "", ok)
+	assert.Equal(t, testCase.resourceGroup, location.ResourceGroup)
+}
+
+// fakeZonesServer is a minimal stand-in for the Azure DNS API that lists a
+// fixed set of zones.
+type fakeZonesServer struct {
+	mu       sync.Mutex
+	zones    []ZoneLocation
+	requests int
+}
+
+func (f *fakeZonesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	fmt.Fprint(w, `{"value": [`)
+	for i, zone := range f.zones {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, `{"id": "/subscriptions/subscription/resourceGroups/%v/providers/Microsoft.Network/dnszones/%v", "name": %q}`,
+			zone.ResourceGroup, zone.Name, zone.Name)
+	}
+	fmt.Fprint(w, `]}`)
+}
+
+func TestDiscoverZone(t *testing.T) {
+	fake := &fakeZonesServer{zones: testZoneLocations[:1]}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := dns.NewZonesClientWithBaseURI(server.URL, "subscription")
+
+	dir, err := ioutil.TempDir("", "az-dns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache := ZoneCache{Path: filepath.Join(dir, "cache", "zones.json"), MaxAge: time.Hour}
+	ctx := context.Background()
+
+	location, err := DiscoverZone(ctx, &client, "", "www.example.com", cache)
+	assert.NoError(t, err)
+	assert.Equal(t, ZoneLocation{"example.com", "dns"}, location)
+	assert.Equal(t, 1, fake.requests)
+
+	// Cached zones are used without listing them again.
+	location, err = DiscoverZone(ctx, &client, "", "www.sub.example.com", cache)
+	assert.NoError(t, err)
+	assert.Equal(t, ZoneLocation{"example.com", "dns"}, location)
+	assert.Equal(t, 1, fake.requests)
+
+	// Zones are listed again if no cached zone matches.
+	fake.zones = testZoneLocations
+	location, err = DiscoverZone(ctx, &client, "", "www.example.org", cache)
+	assert.NoError(t, err)
+	assert.Equal(t, ZoneLocation{"example.org", "dns-org"}, location)
+	assert.Equal(t, 2, fake.requests)
+
+	_, err = DiscoverZone(ctx, &client, "", "www.example.net", cache)
+	assert.Error(t, err)
+	assert.Equal(t, 3, fake.requests)
+
+	// Stale zones are not used.
+	cache.MaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	location, err = DiscoverZone(ctx, &client, "", "www.sub.example.com", cache)
+	assert.NoError(t, err)
+	assert.Equal(t, ZoneLocation{"sub.example.com.", "dns-sub"}, location)
+	assert.Equal(t, 4, fake.requests)
+
+	// The cache can be disabled.
+	cache.MaxAge = 0
+	_, err = DiscoverZone(ctx, &client, "", "www.example.com", cache)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, fake.requests)
+}
@@ -0,0 +1,435 @@
+package helpers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+)
+
+// DefaultQueryTimeout is the time allowed for a single DNS query. A query is
+// also abandoned if the context passed to QueryNameServer is done first.
+const DefaultQueryTimeout = 5 * time.Second
+
+// queryTimeout is the time allowed for a single DNS query; tests shorten it.
+var queryTimeout = DefaultQueryTimeout
+
+// dnsTypeCodes maps record types to their numeric values in DNS messages.
+var dnsTypeCodes = map[dns.RecordType]uint16{
+	dns.A:     1,
+	dns.NS:    2,
+	dns.CNAME: 5,
+	dns.SOA:   6,
+	dns.PTR:   12,
+	dns.MX:    15,
+	dns.TXT:   16,
+	dns.AAAA:  28,
+	dns.SRV:   33,
+	dns.CAA:   257,
+}
+
+const (
+	dnsClassIN         = 1
+	dnsHeaderLength    = 12
+	dnsFlagResponse    = 1 << 15
+	dnsFlagTruncated   = 1 << 9
+	dnsRcodeMask       = 0xf
+	dnsRcodeNameError  = 3
+	dnsMaxUDPMessage   = 512
+	dnsMaxPointerJumps = 64
+)
+
+// QueryNameServer asks the DNS server at server, a host and port, for the
+// records of the given type at fqdn and returns them as ExpectedAnswers would.
+// The query is non-recursive and is sent over UDP, falling back to TCP if the
+// response is truncated. A name that does not exist has no records.
+func QueryNameServer(ctx context.Context, server string, fqdn string, recordType dns.RecordType) ([]string, error) {
+	qtype, ok := dnsTypeCodes[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type %v", recordType)
+	}
+
+	// A query that goes unanswered must not use up all of a longer deadline,
+	// so that WaitForRecords can go on to poll other servers.
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	idBytes := make([]byte, 2)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, err
+	}
+
+	id := binary.BigEndian.Uint16(idBytes)
+	query, err := encodeQuery(id, fqdn, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := exchange(ctx, "udp", server, query)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, truncated, err := decodeResponse(response, id, fqdn, qtype)
+	if err != nil || !truncated {
+		return answers, err
+	}
+
+	response, err = exchange(ctx, "tcp", server, query)
+	if err != nil {
+		return nil, err
+	}
+
+	answers, _, err = decodeResponse(response, id, fqdn, qtype)
+	return answers, err
+}
+
+// exchange sends a DNS message to server over the given network and returns the
+// response. Messages sent over TCP are prefixed with their length.
+func exchange(ctx context.Context, network string, server string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+
+		response := make([]byte, dnsMaxUDPMessage)
+		n, err := conn.Read(response)
+		if err != nil {
+			return nil, err
+		}
+		return response[:n], nil
+	}
+
+	message := make([]byte, 2, 2+len(query))
+	binary.BigEndian.PutUint16(message, uint16(len(query)))
+	if _, err := conn.Write(append(message, query...)); err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+
+	response := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// encodeQuery builds a non-recursive DNS query for records of type qtype at
+// fqdn.
+func encodeQuery(id uint16, fqdn string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, dnsHeaderLength)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[4:], 1)
+
+	name, err := encodeName(fqdn)
+	if err != nil {
+		return nil, err
+	}
+	msg = append(msg, name...)
+
+	question := make([]byte, 4)
+	binary.BigEndian.PutUint16(question[0:], qtype)
+	binary.BigEndian.PutUint16(question[2:], dnsClassIN)
+
+	return append(msg, question...), nil
+}
+
+// encodeName converts a domain name to the sequence of length-prefixed labels
+// used in DNS messages.
+func encodeName(fqdn string) ([]byte, error) {
+	name := []byte{}
+	for _, label := range strings.Split(strings.TrimRight(fqdn, "."), ".") {
+		if label == "" && fqdn != "." {
+			return nil, fmt.Errorf("invalid domain name %q", fqdn)
+		}
+		if len(label) > 63 {
+			return nil, fmt.Errorf("label %q in %v is longer than 63 bytes", label, fqdn)
+		}
+		if label != "" {
+			name = append(name, byte(len(label)))
+			name = append(name, label...)
+		}
+	}
+
+	return append(name, 0), nil
+}
+
+// decodeResponse extracts the records of type qtype owned by fqdn from the
+// answer section of a response to the query with the given ID. It also reports
+// whether the response was truncated.
+func decodeResponse(msg []byte, id uint16, fqdn string, qtype uint16) ([]string, bool, error) {
+	if len(msg) < dnsHeaderLength {
+		return nil, false, fmt.Errorf("DNS response is too short")
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if binary.BigEndian.Uint16(msg[0:]) != id || flags&dnsFlagResponse == 0 {
+		return nil, false, fmt.Errorf("DNS response does not match query")
+	}
+	if flags&dnsFlagTruncated != 0 {
+		return nil, true, nil
+	}
+
+	answers := []string{}
+	switch rcode := flags & dnsRcodeMask; rcode {
+	case 0:
+	case dnsRcodeNameError:
+		return answers, false, nil
+	default:
+		return nil, false, fmt.Errorf("DNS server returned error code %v", rcode)
+	}
+
+	questions := binary.BigEndian.Uint16(msg[4:])
+	records := binary.BigEndian.Uint16(msg[6:])
+	offset := dnsHeaderLength
+
+	for i := 0; i < int(questions); i++ {
+		_, next, err := decodeName(msg, offset)
+		if err != nil {
+			return nil, false, err
+		}
+		offset = next + 4
+	}
+
+	owner := strings.ToLower(Fqdn(fqdn))
+	for i := 0; i < int(records); i++ {
+		name, next, err := decodeName(msg, offset)
+		if err != nil {
+			return nil, false, err
+		}
+		if next+10 > len(msg) {
+			return nil, false, fmt.Errorf("DNS response is truncated")
+		}
+
+		rrtype := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		offset = next + 10
+		if offset+length > len(msg) {
+			return nil, false, fmt.Errorf("DNS response is truncated")
+		}
+
+		if rrtype == qtype && strings.ToLower(name) == owner {
+			value, err := decodeRecordData(msg, offset, length, qtype)
+			if err != nil {
+				return nil, false, err
+			}
+			answers = append(answers, value)
+		}
+		offset += length
+	}
+
+	return answers, false, nil
+}
+
+// decodeName reads a possibly-compressed domain name starting at offset in msg
+// and returns it, fully-qualified, along with the offset following it.
+func decodeName(msg []byte, offset int) (string, int, error) {
+	labels := []string{}
+	end := -1
+
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, fmt.Errorf("DNS response is truncated")
+		}
+
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) {
+				return "", 0, fmt.Errorf("DNS response is truncated")
+			}
+			if jumps++; jumps > dnsMaxPointerJumps {
+				return "", 0, fmt.Errorf("DNS response contains a compression loop")
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+		case length > 63:
+			return "", 0, fmt.Errorf("DNS response contains an invalid label")
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, fmt.Errorf("DNS response is truncated")
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// decodeRecordData formats the RDATA of a record of type qtype in the same way
+// as ExpectedAnswers.
+func decodeRecordData(msg []byte, offset int, length int, qtype uint16) (string, error) {
+	rdata := msg[offset : offset+length]
+	invalid := fmt.Errorf("DNS response contains an invalid record of type %v", qtype)
+
+	name := func(at int) (string, error) {
+		value, _, err := decodeName(msg, offset+at)
+		return strings.ToLower(value), err
+	}
+
+	switch qtype {
+	case dnsTypeCodes[dns.A], dnsTypeCodes[dns.AAAA]:
+		if length != net.IPv4len && length != net.IPv6len {
+			return "", invalid
+		}
+		return net.IP(rdata).String(), nil
+	case dnsTypeCodes[dns.CNAME], dnsTypeCodes[dns.NS], dnsTypeCodes[dns.PTR]:
+		return name(0)
+	case dnsTypeCodes[dns.MX]:
+		if length < 3 {
+			return "", invalid
+		}
+		exchange, err := name(2)
+		return fmt.Sprintf("%v %v", binary.BigEndian.Uint16(rdata), exchange), err
+	case dnsTypeCodes[dns.SRV]:
+		if length < 7 {
+			return "", invalid
+		}
+		target, err := name(6)
+		return fmt.Sprintf("%v %v %v %v", binary.BigEndian.Uint16(rdata), binary.BigEndian.Uint16(rdata[2:]),
+			binary.BigEndian.Uint16(rdata[4:]), target), err
+	case dnsTypeCodes[dns.TXT]:
+		quoted := []string{}
+		for i := 0; i < length; {
+			size := int(rdata[i])
+			if i+1+size > length {
+				return "", invalid
+			}
+			quoted = append(quoted, QuoteCharacterString(string(rdata[i+1:i+1+size])))
+			i += 1 + size
+		}
+		return strings.Join(quoted, " "), nil
+	case dnsTypeCodes[dns.CAA]:
+		if length < 2 || 2+int(rdata[1]) > length {
+			return "", invalid
+		}
+		tagEnd := 2 + int(rdata[1])
+		return fmt.Sprintf("%v %v %v", rdata[0], strings.ToLower(string(rdata[2:tagEnd])),
+			QuoteCharacterString(string(rdata[tagEnd:]))), nil
+	}
+
+	return "", invalid
+}
+
+// ExpectedAnswers returns the records in props in the form returned by
+// QueryNameServer, so that the two can be compared.
+func ExpectedAnswers(recordType dns.RecordType, props *dns.RecordSetProperties) []string {
+	values := recordKeys(recordType, props)
+	for i, value := range values {
+		switch recordType {
+		case dns.A, dns.AAAA:
+			if ip := net.ParseIP(value); ip != nil {
+				values[i] = ip.String()
+			}
+		case dns.CAA:
+			// Tags are case-insensitive, but values are not.
+			fields := strings.SplitN(value, " ", 3)
+			if len(fields) == 3 {
+				values[i] = fields[0] + " " + strings.ToLower(fields[1]) + " " + fields[2]
+			}
+		}
+	}
+
+	return values
+}
+
+// WaitForRecords repeatedly queries each of the given DNS servers until every
+// one of them has the expected records of the given type at fqdn, or ctx is
+// done. If present is true, each server's answer must include all of expected,
+// which should come from ExpectedAnswers. Otherwise, it must include none of
+// them, or no records at all if expected is empty. Servers are queried again
+// after each interval until they agree.
+func WaitForRecords(ctx context.Context, servers []string, fqdn string, recordType dns.RecordType, expected []string,
+	present bool, interval time.Duration) error {
+	pending := map[string]bool{}
+	for _, server := range servers {
+		pending[server] = true
+	}
+
+	var lastErr error
+	for {
+		for _, server := range servers {
+			if !pending[server] {
+				continue
+			}
+
+			answers, err := QueryNameServer(ctx, server, fqdn, recordType)
+			if err != nil {
+				lastErr = fmt.Errorf("%v: %v", server, err)
+				continue
+			}
+
+			if answersMatch(answers, expected, present) {
+				delete(pending, server)
+			}
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			waiting := []string{}
+			for _, server := range servers {
+				if pending[server] {
+					waiting = append(waiting, server)
+				}
+			}
+
+			err := fmt.Errorf("timed out waiting for %v records at %v on %v", recordType, fqdn, strings.Join(waiting, ", "))
+			if lastErr != nil {
+				err = fmt.Errorf("%v (last error: %v)", err, lastErr)
+			}
+			return err
+		}
+	}
+}
+
+// answersMatch reports whether answers satisfy WaitForRecords' conditions.
+func answersMatch(answers []string, expected []string, present bool) bool {
+	found := map[string]bool{}
+	for _, answer := range answers {
+		found[answer] = true
+	}
+
+	if !present && len(expected) == 0 {
+		return len(answers) == 0
+	}
+
+	for _, value := range expected {
+		if found[value] != present {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,377 @@
+package helpers
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubDNSServer answers DNS queries over UDP and TCP on the same port with a
+// fixed set of records for every name.
+type stubDNSServer struct {
+	mu sync.Mutex
+	// answers holds the RDATA of the records of each type.
+	answers map[uint16][][]byte
+	// rcode is the response code returned for every query.
+	rcode uint16
+	// truncateUDP causes every UDP response to be truncated.
+	truncateUDP bool
+	queries     int
+
+	udp net.PacketConn
+	tcp net.Listener
+}
+
+func newStubDNSServer(t *testing.T) *stubDNSServer {
+	server := &stubDNSServer{answers: map[uint16][][]byte{}}
+
+	for attempt := 0; server.udp == nil; attempt++ {
+		tcp, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		udp, err := net.ListenPacket("udp", tcp.Addr().String())
+		if err != nil {
+			tcp.Close()
+			if attempt > 10 {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		server.tcp, server.udp = tcp, udp
+	}
+
+	go server.serveUDP()
+	go server.serveTCP()
+
+	return server
+}
+
+func (s *stubDNSServer) Addr() string {
+	return s.tcp.Addr().String()
+}
+
+func (s *stubDNSServer) Close() {
+	s.udp.Close()
+	s.tcp.Close()
+}
+
+func (s *stubDNSServer) setAnswers(qtype uint16, rdata ...[]byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.answers[qtype] = rdata
+}
+
+func (s *stubDNSServer) setRcode(rcode uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rcode = rcode
+}
+
+func (s *stubDNSServer) queryCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+func (s *stubDNSServer) serveUDP() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		s.udp.WriteTo(s.respond(buf[:n], true), addr)
+	}
+}
+
+func (s *stubDNSServer) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+
+		length := make([]byte, 2)
+		if _, err := io.ReadFull(conn, length); err == nil {
+			query := make([]byte, binary.BigEndian.Uint16(length))
+			if _, err := io.ReadFull(conn, query); err == nil {
+				response := s.respond(query, false)
+				binary.BigEndian.PutUint16(length, uint16(len(response)))
+				conn.Write(append(length, response...))
+			}
+		}
+		conn.Close()
+	}
+}
+
+// respond builds the response to a query containing a single question. Owner
+// names in the answers are compressed as pointers to the question.
+func (s *stubDNSServer) respond(query []byte, udp bool) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+
+	_, end, _ := decodeName(query, dnsHeaderLength)
+	qtype := binary.BigEndian.Uint16(query[end:])
+
+	flags := uint16(dnsFlagResponse|1<<10) | s.rcode
+	answers := s.answers[qtype]
+	if udp && s.truncateUDP {
+		flags |= dnsFlagTruncated
+		answers = nil
+	}
+
+	response := make([]byte, dnsHeaderLength)
+	copy(response, query[:2])
+	binary.BigEndian.PutUint16(response[2:], flags)
+	binary.BigEndian.PutUint16(response[4:], 1)
+	binary.BigEndian.PutUint16(response[6:], uint16(len(answers)))
+	response = append(response, query[dnsHeaderLength:end+4]...)
+
+	for _, rdata := range answers {
+		record := make([]byte, 12)
+		binary.BigEndian.PutUint16(record[0:], 0xc000|dnsHeaderLength)
+		binary.BigEndian.PutUint16(record[2:], qtype)
+		binary.BigEndian.PutUint16(record[4:], dnsClassIN)
+		binary.BigEndian.PutUint32(record[6:], 300)
+		binary.BigEndian.PutUint16(record[10:], uint16(len(rdata)))
+		response = append(append(response, record...), rdata...)
+	}
+
+	return response
+}
+
+func txtRData(values ...string) []byte {
+	rdata := []byte{}
+	for _, value := range values {
+		rdata = append(append(rdata, byte(len(value))), value...)
+	}
+	return rdata
+}
+
+func mxRData(preference uint16, exchange string) []byte {
+	rdata := make([]byte, 2)
+	binary.BigEndian.PutUint16(rdata, preference)
+	name, _ := encodeName(exchange)
+	return append(rdata, name...)
+}
+
+func TestQueryNameServer(t *testing.T) {
+	server := newStubDNSServer(t)
+	defer server.Close()
+
+	server.setAnswers(dnsTypeCodes[dns.A], net.ParseIP("192.0.2.1").To4(), net.ParseIP("192.0.2.2").To4())
+	server.setAnswers(dnsTypeCodes[dns.AAAA], net.ParseIP("2001:db8::1"))
+	server.setAnswers(dnsTypeCodes[dns.TXT], txtRData("first", "second"), txtRData(`with "quotes"`))
+	server.setAnswers(dnsTypeCodes[dns.MX], mxRData(10, "MAIL.example.com"))
+
+	ctx := context.Background()
+
+	answers, err := QueryNameServer(ctx, server.Addr(), "www.example.com", dns.A)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1", "192.0.2.2"}, answers)
+
+	answers, err = QueryNameServer(ctx, server.Addr(), "www.example.com.", dns.AAAA)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8::1"}, answers)
+
+	answers, err = QueryNameServer(ctx, server.Addr(), "www.example.com", dns.TXT)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`"first" "second"`, `"with \"quotes\""`}, answers)
+
+	answers, err = QueryNameServer(ctx, server.Addr(), "example.com", dns.MX)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10 mail.example.com."}, answers)
+
+	answers, err = QueryNameServer(ctx, server.Addr(), "www.example.com", dns.CNAME)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, answers)
+}
+
+func TestQueryNameServerTCPFallback(t *testing.T) {
+	server := newStubDNSServer(t)
+	defer server.Close()
+
+	server.mu.Lock()
+	server.truncateUDP = true
+	server.mu.Unlock()
+	server.setAnswers(dnsTypeCodes[dns.TXT], txtRData("token"))
+
+	answers, err := QueryNameServer(context.Background(), server.Addr(), "_acme-challenge.example.com", dns.TXT)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`"token"`}, answers)
+	assert.Equal(t, 2, server.queryCount())
+}
+
+func TestQueryNameServerErrors(t *testing.T) {
+	server := newStubDNSServer(t)
+	defer server.Close()
+
+	server.setAnswers(dnsTypeCodes[dns.A], net.ParseIP("192.0.2.1").To4())
+
+	server.setRcode(dnsRcodeNameError)
+	answers, err := QueryNameServer(context.Background(), server.Addr(), "missing.example.com", dns.A)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, answers)
+
+	// REFUSED
+	server.setRcode(5)
+	_, err = QueryNameServer(context.Background(), server.Addr(), "www.example.com", dns.A)
+	assert.Error(t, err)
+
+	_, err = QueryNameServer(context.Background(), server.Addr(), "www.example.com", dns.SOA+"X")
+	assert.Error(t, err)
+}
+
+// newSilentDNSServer returns the address of a UDP socket that never answers.
+func newSilentDNSServer(t *testing.T) (string, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func TestQueryNameServerTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { queryTimeout = timeout }(queryTimeout)
+	queryTimeout = 50 * time.Millisecond
+
+	silent, closeSilent := newSilentDNSServer(t)
+	defer closeSilent()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := QueryNameServer(ctx, silent, "www.example.com", dns.A)
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < time.Second, "query was not abandoned after queryTimeout")
+}
+
+func TestDecodeNameCompressionLoop(t *testing.T) {
+	msg := make([]byte, dnsHeaderLength+2)
+	binary.BigEndian.PutUint16(msg[dnsHeaderLength:], 0xc000|dnsHeaderLength)
+
+	_, _, err := decodeName(msg, dnsHeaderLength)
+	assert.Error(t, err)
+}
+
+func TestExpectedAnswers(t *testing.T) {
+	assert.Equal(t, []string{"2001:db8::1"}, ExpectedAnswers(dns.AAAA, &dns.RecordSetProperties{
+		AaaaRecords: &[]dns.AaaaRecord{{Ipv6Address: to.StringPtr("2001:0DB8:0:0::1")}},
+	}))
+	assert.Equal(t, []string{"10 mail.example.com."}, ExpectedAnswers(dns.MX, &dns.RecordSetProperties{
+		MxRecords: &[]dns.MxRecord{{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("MAIL.example.com")}},
+	}))
+	assert.Equal(t, []string{`0 issue "LetsEncrypt.org"`}, ExpectedAnswers(dns.CAA, &dns.RecordSetProperties{
+		CaaRecords: &[]dns.CaaRecord{{Flags: to.Int32Ptr(0), Tag: to.StringPtr("Issue"), Value: to.StringPtr("LetsEncrypt.org")}},
+	}))
+}
+
+func TestWaitForRecords(t *testing.T) {
+	first := newStubDNSServer(t)
+	defer first.Close()
+	second := newStubDNSServer(t)
+	defer second.Close()
+
+	servers := []string{first.Addr(), second.Addr()}
+	txt := dnsTypeCodes[dns.TXT]
+	first.setAnswers(txt, txtRData("other"), txtRData("token"))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		second.setAnswers(txt, txtRData("token"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := WaitForRecords(ctx, servers, "_acme-challenge.example.com", dns.TXT, []string{`"token"`}, true, 10*time.Millisecond)
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		first.setAnswers(txt, txtRData("other"))
+		second.setAnswers(txt)
+	}()
+
+	err = WaitForRecords(ctx, servers, "_acme-challenge.example.com", dns.TXT, []string{`"token"`}, false, 10*time.Millisecond)
+	assert.NoError(t, err)
+}
+
+func TestWaitForRecordsTimeout(t *testing.T) {
+	server := newStubDNSServer(t)
+	defer server.Close()
+
+	server.setAnswers(dnsTypeCodes[dns.A], net.ParseIP("192.0.2.1").To4())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := WaitForRecords(ctx, []string{server.Addr()}, "www.example.com", dns.A, []string{}, false, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+func TestWaitForRecordsUnresponsiveServer(t *testing.T) {
+	defer func(timeout time.Duration) { queryTimeout = timeout }(queryTimeout)
+	queryTimeout = 50 * time.Millisecond
+
+	silent, closeSilent := newSilentDNSServer(t)
+	defer closeSilent()
+	server := newStubDNSServer(t)
+	defer server.Close()
+
+	server.setAnswers(dnsTypeCodes[dns.A], net.ParseIP("192.0.2.1").To4())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// The silent server is queried first; the other must still be polled, and
+	// found to agree, before the outer deadline.
+	err := WaitForRecords(ctx, []string{silent, server.Addr()}, "www.example.com", dns.A,
+		[]string{"192.0.2.1"}, true, 10*time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), silent)
+		assert.NotContains(t, err.Error(), server.Addr())
+	}
+	assert.Equal(t, 1, server.queryCount())
+}
+
+type answersMatchTestCase struct {
+	name     string
+	answers  []string
+	expected []string
+	present  bool
+	matches  bool
+}
+
+var answersMatchTests = []answersMatchTestCase{
+	{"all present", []string{"a", "b", "c"}, []string{"a", "b"}, true, true},
+	{"one missing", []string{"a", "c"}, []string{"a", "b"}, true, false},
+	{"none present", []string{"c"}, []string{"a", "b"}, false, true},
+	{"one still present", []string{"b", "c"}, []string{"a", "b"}, false, false},
+	{"record set gone", []string{}, []string{}, false, true},
+	{"record set remains", []string{"a"}, []string{}, false, false},
+}
+
+func TestAnswersMatch(t *testing.T) {
+	for _, testCase := range answersMatchTests {
+		t.Run(testCase.name, func(t *testing.T) { testAnswersMatch(t, testCase) })
+	}
+}
+
+func testAnswersMatch(t *testing.T, testCase answersMatchTestCase) {
+	assert.Equal(t, testCase.matches, answersMatch(testCase.answers, testCase.expected, testCase.present))
+}
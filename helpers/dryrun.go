@@ -0,0 +1,47 @@
+package helpers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/spf13/viper"
+)
+
+// DryRunError is returned for a request that would have changed something
+// while the dry-run option is set.
+type DryRunError struct {
+	Method string
+	Path   string
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("refusing to send %v %v in dry-run mode", e.Method, e.Path)
+}
+
+// readOnlySender passes on requests that only read, and refuses all others.
+type readOnlySender struct {
+	sender autorest.Sender
+}
+
+// NewReadOnlySender returns a Sender that sends GET and HEAD requests with
+// sender and fails any other request with a *DryRunError, without sending it.
+func NewReadOnlySender(sender autorest.Sender) autorest.Sender {
+	return &readOnlySender{sender: sender}
+}
+
+func (s *readOnlySender) Do(r *http.Request) (*http.Response, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return nil, &DryRunError{Method: r.Method, Path: r.URL.Path}
+	}
+
+	return s.sender.Do(r)
+}
+
+// applyDryRun makes client read-only if the dry-run option is set, so that a
+// command that does not support dry runs fails before it changes anything.
+func applyDryRun(client *autorest.Client) {
+	if viper.GetBool("dry-run") {
+		client.Sender = NewReadOnlySender(client.Sender)
+	}
+}
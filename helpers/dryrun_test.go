@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingSender struct {
+	requests int
+}
+
+func (s *countingSender) Do(r *http.Request) (*http.Response, error) {
+	s.requests++
+	return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+}
+
+func TestReadOnlySender(t *testing.T) {
+	inner := &countingSender{}
+	sender := NewReadOnlySender(inner)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		req, _ := http.NewRequest(method, "https://management.azure.com/zones", nil)
+		resp, err := sender.Do(req)
+		assert.NoError(t, err, method)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, method)
+	}
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodPost} {
+		req, _ := http.NewRequest(method, "https://management.azure.com/zones", nil)
+		_, err := sender.Do(req)
+		assert.Equal(t, &DryRunError{Method: method, Path: "/zones"}, err, method)
+	}
+
+	assert.Equal(t, 2, inner.requests)
+}
@@ -0,0 +1,146 @@
+package helpers
+
+import (
+	"net/http"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+)
+
+// CredentialsError indicates that credentials for Azure could not be found or
+// used.
+type CredentialsError struct {
+	Err error
+}
+
+func (e *CredentialsError) Error() string {
+	return e.Err.Error()
+}
+
+// AzureErrorInfo summarizes an error returned by an Azure API call.
+type AzureErrorInfo struct {
+	// StatusCode is the HTTP status code of the response, or 0 if the request
+	// failed without one.
+	StatusCode int
+	// Code is the Azure Resource Manager error code, such as ResourceNotFound,
+	// if the response contained one.
+	Code string
+	// Message is the error message from Azure Resource Manager if there was
+	// one, or otherwise that of the underlying error, without the context
+	// added by the SDK.
+	Message string
+	// RequestID is the value of the response's x-ms-request-id header.
+	RequestID string
+	// Authorization indicates that the request failed because an access token
+	// could not be obtained.
+	Authorization bool
+}
+
+// DescribeAzureError unwraps the autorest.DetailedError and azure.RequestError
+// values returned by the SDK to find the details of an error. If err did not
+// come from an Azure API call, false is returned.
+func DescribeAzureError(err error) (AzureErrorInfo, bool) {
+	var info AzureErrorInfo
+	found := false
+
+	for err != nil {
+		var detailed *autorest.DetailedError
+		switch e := err.(type) {
+		case autorest.DetailedError:
+			detailed = &e
+		case *autorest.DetailedError:
+			detailed = e
+		case azure.RequestError:
+			describeRequestError(&info, &e)
+			return info, true
+		case *azure.RequestError:
+			describeRequestError(&info, e)
+			return info, true
+		case adal.TokenRefreshError:
+			info.Authorization = true
+			info.Message = e.Error()
+			if resp := e.Response(); resp != nil && info.StatusCode == 0 {
+				info.StatusCode = resp.StatusCode
+			}
+			return info, true
+		}
+
+		if detailed == nil {
+			info.Message = err.Error()
+			return info, found
+		}
+
+		found = true
+		if detailed.PackageType == "azure.BearerAuthorizer" {
+			info.Authorization = true
+		}
+		if code, ok := detailed.StatusCode.(int); ok && code != 0 {
+			info.StatusCode = code
+		}
+		if detailed.Response != nil && info.RequestID == "" {
+			info.RequestID = azure.ExtractRequestID(detailed.Response)
+		}
+
+		if detailed.Original == nil {
+			info.Message = detailed.Message
+			return info, found
+		}
+		err = detailed.Original
+	}
+
+	return info, found
+}
+
+// describeRequestError adds the details of an error response from Azure
+// Resource Manager to info.
+func describeRequestError(info *AzureErrorInfo, e *azure.RequestError) {
+	if code, ok := e.StatusCode.(int); ok && code != 0 {
+		info.StatusCode = code
+	}
+	if e.RequestID != "" {
+		info.RequestID = e.RequestID
+	}
+
+	info.Message = e.Error()
+	if e.ServiceError != nil {
+		info.Code = e.ServiceError.Code
+		if e.ServiceError.Message != "" {
+			info.Message = e.ServiceError.Message
+		}
+	}
+}
+
+// IsNotFound reports whether err indicates that a zone, record set, or other
+// resource does not exist.
+func IsNotFound(err error) bool {
+	switch err.(type) {
+	case *ZoneNotFoundError, *RecordSetNotFoundError:
+		return true
+	}
+
+	info, ok := DescribeAzureError(err)
+	return ok && !info.Authorization && info.StatusCode == http.StatusNotFound
+}
+
+// IsAuthFailure reports whether err was caused by missing or invalid
+// credentials, or by a lack of permission.
+func IsAuthFailure(err error) bool {
+	if _, ok := err.(*CredentialsError); ok {
+		return true
+	}
+
+	info, ok := DescribeAzureError(err)
+	if !ok {
+		return false
+	}
+
+	return info.Authorization || info.StatusCode == http.StatusUnauthorized || info.StatusCode == http.StatusForbidden
+}
+
+// IsThrottled reports whether err was caused by Azure Resource Manager
+// throttling requests.
+func IsThrottled(err error) bool {
+	info, ok := DescribeAzureError(err)
+	return ok && !info.Authorization && info.StatusCode == http.StatusTooManyRequests
+}
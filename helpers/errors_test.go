@@ -0,0 +1,101 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/stretchr/testify/assert"
+)
+
+type azureErrorTestCase struct {
+	name       string
+	statusCode int
+	body       string
+	info       AzureErrorInfo
+	notFound   bool
+	auth       bool
+	throttled  bool
+}
+
+var azureErrorTests = []azureErrorTestCase{
+	{
+		name:       "not found",
+		statusCode: http.StatusNotFound,
+		body:       `{"error": {"code": "NotFound", "message": "The resource was not found."}}`,
+		info:       AzureErrorInfo{StatusCode: 404, Code: "NotFound", Message: "The resource was not found.", RequestID: "request"},
+		notFound:   true,
+	},
+	{
+		name:       "forbidden",
+		statusCode: http.StatusForbidden,
+		body:       `{"error": {"code": "AuthorizationFailed", "message": "Not allowed."}}`,
+		info:       AzureErrorInfo{StatusCode: 403, Code: "AuthorizationFailed", Message: "Not allowed.", RequestID: "request"},
+		auth:       true,
+	},
+	{
+		name:       "throttled",
+		statusCode: http.StatusTooManyRequests,
+		body:       `{"code": "TooManyRequests", "message": "Slow down."}`,
+		info:       AzureErrorInfo{StatusCode: 429, Code: "TooManyRequests", Message: "Slow down.", RequestID: "request"},
+		throttled:  true,
+	},
+}
+
+func TestDescribeAzureError(t *testing.T) {
+	for _, testCase := range azureErrorTests {
+		t.Run(testCase.name, func(t *testing.T) { testDescribeAzureError(t, testCase) })
+	}
+}
+
+func testDescribeAzureError(t *testing.T, testCase azureErrorTestCase) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Ms-Request-Id", "request")
+		w.WriteHeader(testCase.statusCode)
+		fmt.Fprint(w, testCase.body)
+	}))
+	defer server.Close()
+
+	client := dns.NewRecordSetsClientWithBaseURI(server.URL, "subscription")
+	RetryPolicy{}.Apply(&client.Client)
+
+	_, err := client.Get(context.Background(), "dns", "example.com", "www", dns.A)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	info, ok := DescribeAzureError(err)
+	assert.True(t, ok)
+	assert.Equal(t, testCase.info, info)
+	assert.Equal(t, testCase.notFound, IsNotFound(err))
+	assert.Equal(t, testCase.auth, IsAuthFailure(err))
+	assert.Equal(t, testCase.throttled, IsThrottled(err))
+}
+
+func TestDescribeAzureErrorTokenRefresh(t *testing.T) {
+	err := autorest.NewErrorWithError(
+		autorest.NewErrorWithError(errors.New("token expired"), "azure.BearerAuthorizer", "WithAuthorization", nil, "Failed to refresh the Token"),
+		"dns.RecordSetsClient", "Get", nil, "Failure preparing request")
+
+	info, ok := DescribeAzureError(err)
+	assert.True(t, ok)
+	assert.True(t, info.Authorization)
+	assert.Equal(t, "token expired", info.Message)
+	assert.True(t, IsAuthFailure(err))
+	assert.False(t, IsNotFound(err))
+}
+
+func TestDescribeAzureErrorOther(t *testing.T) {
+	_, ok := DescribeAzureError(errors.New("something else"))
+	assert.False(t, ok)
+
+	assert.True(t, IsNotFound(&ZoneNotFoundError{Name: "example.com"}))
+	assert.True(t, IsAuthFailure(&CredentialsError{Err: errors.New("no credentials")}))
+	assert.Equal(t, "no DNS zone containing www.example.com was found", (&ZoneNotFoundError{Name: "www.example.com", Containing: true}).Error())
+}
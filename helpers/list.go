@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+)
+
+// ListRecordSets retrieves every record set in a DNS zone, following the
+// service's continuation links until the listing is complete. If recordType is
+// not empty, only record sets of that type are returned. top, if not nil, sets
+// the number of record sets requested per page, and nameSuffix restricts the
+// listing to record sets whose names end with .nameSuffix.
+func ListRecordSets(ctx context.Context, client *dns.RecordSetsClient, resourceGroup string, zone string, recordType dns.RecordType, top *int32, nameSuffix string) ([]dns.RecordSet, error) {
+	var iter dns.RecordSetListResultIterator
+	var err error
+
+	if recordType == "" {
+		iter, err = client.ListByDNSZoneComplete(ctx, resourceGroup, zone, top, nameSuffix)
+	} else {
+		iter, err = client.ListByTypeComplete(ctx, resourceGroup, zone, recordType, top, nameSuffix)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rrsets := []dns.RecordSet{}
+	for iter.NotDone() {
+		rrsets = append(rrsets, iter.Value())
+		if err := iter.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	return rrsets, nil
+}
+
+// ListZones retrieves every DNS zone in a resource group, or in the entire
+// subscription if resourceGroup is empty.
+func ListZones(ctx context.Context, client *dns.ZonesClient, resourceGroup string) ([]dns.Zone, error) {
+	var iter dns.ZoneListResultIterator
+	var err error
+
+	if resourceGroup == "" {
+		iter, err = client.ListComplete(ctx, nil)
+	} else {
+		iter, err = client.ListByResourceGroupComplete(ctx, resourceGroup, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	zones := []dns.Zone{}
+	for iter.NotDone() {
+		zones = append(zones, iter.Value())
+		if err := iter.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	return zones, nil
+}
@@ -0,0 +1,184 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+)
+
+// AddRecords returns a copy of props to which each record in additions that is
+// not already present has been appended. The TTL and metadata of props are kept;
+// if props is nil, those of additions are used. Because a CNAME record set can
+// hold only one record, adding a CNAME record to a set that already has a
+// different one is an error.
+func AddRecords(recordType dns.RecordType, props *dns.RecordSetProperties, additions *dns.RecordSetProperties) (*dns.RecordSetProperties, error) {
+	if props == nil {
+		props, additions = additions, nil
+	}
+
+	present := map[string]bool{}
+	for _, key := range recordKeys(recordType, props) {
+		present[key] = true
+	}
+
+	additionKeys := recordKeys(recordType, additions)
+	result := combineRecords(recordType, props, additions, func(int) bool { return true }, func(i int) bool {
+		key := additionKeys[i]
+		if present[key] {
+			return false
+		}
+		present[key] = true
+		return true
+	})
+
+	if recordType == dns.CNAME && len(present) > 1 {
+		return nil, fmt.Errorf("a CNAME record set can only contain one record")
+	}
+
+	return result, nil
+}
+
+// RemoveRecords returns a copy of props from which every record equivalent to
+// one in removals has been removed, along with the number of records that
+// remain. If props is nil, so is the result.
+func RemoveRecords(recordType dns.RecordType, props *dns.RecordSetProperties, removals *dns.RecordSetProperties) (*dns.RecordSetProperties, int) {
+	if props == nil {
+		return nil, 0
+	}
+
+	removed := map[string]bool{}
+	for _, key := range recordKeys(recordType, removals) {
+		removed[key] = true
+	}
+
+	keys := recordKeys(recordType, props)
+	remaining := 0
+	result := combineRecords(recordType, props, nil, func(i int) bool {
+		if removed[keys[i]] {
+			return false
+		}
+		remaining++
+		return true
+	}, nil)
+
+	return result, remaining
+}
+
+// combineRecords returns a copy of base, which must not be nil, whose records
+// are those of base for which keepBase returns true followed by those of extra
+// for which keepExtra returns true. Each function is passed the index of a
+// record within its record set.
+func combineRecords(recordType dns.RecordType, base *dns.RecordSetProperties, extra *dns.RecordSetProperties,
+	keepBase func(int) bool, keepExtra func(int) bool) *dns.RecordSetProperties {
+	result := *base
+	sources := []*dns.RecordSetProperties{base, extra}
+	keeps := []func(int) bool{keepBase, keepExtra}
+
+	switch recordType {
+	case dns.A:
+		records := []dns.ARecord{}
+		for n, props := range sources {
+			if props != nil && props.ARecords != nil {
+				for i, record := range *props.ARecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.ARecords = &records
+	case dns.AAAA:
+		records := []dns.AaaaRecord{}
+		for n, props := range sources {
+			if props != nil && props.AaaaRecords != nil {
+				for i, record := range *props.AaaaRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.AaaaRecords = &records
+	case dns.CAA:
+		records := []dns.CaaRecord{}
+		for n, props := range sources {
+			if props != nil && props.CaaRecords != nil {
+				for i, record := range *props.CaaRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.CaaRecords = &records
+	case dns.CNAME:
+		result.CnameRecord = nil
+		for n, props := range sources {
+			if props != nil && props.CnameRecord != nil && keeps[n](0) && result.CnameRecord == nil {
+				result.CnameRecord = props.CnameRecord
+			}
+		}
+	case dns.MX:
+		records := []dns.MxRecord{}
+		for n, props := range sources {
+			if props != nil && props.MxRecords != nil {
+				for i, record := range *props.MxRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.MxRecords = &records
+	case dns.NS:
+		records := []dns.NsRecord{}
+		for n, props := range sources {
+			if props != nil && props.NsRecords != nil {
+				for i, record := range *props.NsRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.NsRecords = &records
+	case dns.PTR:
+		records := []dns.PtrRecord{}
+		for n, props := range sources {
+			if props != nil && props.PtrRecords != nil {
+				for i, record := range *props.PtrRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.PtrRecords = &records
+	case dns.SRV:
+		records := []dns.SrvRecord{}
+		for n, props := range sources {
+			if props != nil && props.SrvRecords != nil {
+				for i, record := range *props.SrvRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.SrvRecords = &records
+	case dns.TXT:
+		records := []dns.TxtRecord{}
+		for n, props := range sources {
+			if props != nil && props.TxtRecords != nil {
+				for i, record := range *props.TxtRecords {
+					if keeps[n](i) {
+						records = append(records, record)
+					}
+				}
+			}
+		}
+		result.TxtRecords = &records
+	}
+
+	return &result
+}
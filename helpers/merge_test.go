@@ -0,0 +1,102 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+func txtProps(ttl int64, values ...string) *dns.RecordSetProperties {
+	records := []dns.TxtRecord{}
+	for _, value := range values {
+		records = append(records, dns.TxtRecord{Value: &[]string{value}})
+	}
+
+	return &dns.RecordSetProperties{TTL: to.Int64Ptr(ttl), TxtRecords: &records}
+}
+
+func TestAddRecords(t *testing.T) {
+	current := txtProps(60, "token1", "token2")
+	current.Metadata = &map[string]*string{"owner": to.StringPtr("acme")}
+
+	result, err := AddRecords(dns.TXT, current, txtProps(300, "token2", "token3", "token3", "Token1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`"token1"`, `"token2"`, `"token3"`, `"Token1"`}, RecordValues(dns.TXT, result))
+	assert.Equal(t, int64(60), to.Int64(result.TTL))
+	assert.Equal(t, current.Metadata, result.Metadata)
+
+	// The original must not be modified.
+	assert.Equal(t, []string{`"token1"`, `"token2"`}, RecordValues(dns.TXT, current))
+}
+
+func TestAddRecordsToMissingSet(t *testing.T) {
+	result, err := AddRecords(dns.TXT, nil, txtProps(300, "token"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`"token"`}, RecordValues(dns.TXT, result))
+	assert.Equal(t, int64(300), to.Int64(result.TTL))
+}
+
+func TestAddRecordsNormalizesDomainNames(t *testing.T) {
+	current := &dns.RecordSetProperties{
+		MxRecords: &[]dns.MxRecord{{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("mail.example.com")}},
+	}
+	additions := &dns.RecordSetProperties{
+		MxRecords: &[]dns.MxRecord{
+			{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("MAIL.example.com.")},
+			{Preference: to.Int32Ptr(20), Exchange: to.StringPtr("mail.example.com")},
+		},
+	}
+
+	result, err := AddRecords(dns.MX, current, additions)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"10 mail.example.com", "20 mail.example.com"}, RecordValues(dns.MX, result))
+}
+
+func TestAddRecordsCNAME(t *testing.T) {
+	current := &dns.RecordSetProperties{CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.com")}}
+
+	result, err := AddRecords(dns.CNAME, current, &dns.RecordSetProperties{
+		CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.com.")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com"}, RecordValues(dns.CNAME, result))
+
+	_, err = AddRecords(dns.CNAME, current, &dns.RecordSetProperties{
+		CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.org")},
+	})
+	assert.Error(t, err)
+}
+
+func TestRemoveRecords(t *testing.T) {
+	current := txtProps(60, "token1", "token2", "token3")
+
+	result, remaining := RemoveRecords(dns.TXT, current, txtProps(300, "token2", "missing"))
+	assert.Equal(t, 2, remaining)
+	assert.Equal(t, []string{`"token1"`, `"token3"`}, RecordValues(dns.TXT, result))
+	assert.Equal(t, int64(60), to.Int64(result.TTL))
+
+	result, remaining = RemoveRecords(dns.TXT, result, txtProps(300, "token1", "token3"))
+	assert.Equal(t, 0, remaining)
+	assert.Equal(t, []string{}, RecordValues(dns.TXT, result))
+
+	result, remaining = RemoveRecords(dns.TXT, nil, txtProps(300, "token1"))
+	assert.Nil(t, result)
+	assert.Equal(t, 0, remaining)
+}
+
+func TestRemoveRecordsCNAME(t *testing.T) {
+	current := &dns.RecordSetProperties{CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.com")}}
+
+	result, remaining := RemoveRecords(dns.CNAME, current, &dns.RecordSetProperties{
+		CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.org")},
+	})
+	assert.Equal(t, 1, remaining)
+	assert.Equal(t, []string{"example.com"}, RecordValues(dns.CNAME, result))
+
+	_, remaining = RemoveRecords(dns.CNAME, current, &dns.RecordSetProperties{
+		CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("EXAMPLE.com")},
+	})
+	assert.Equal(t, 0, remaining)
+}
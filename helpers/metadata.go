@@ -0,0 +1,188 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// RecordSetNotFoundError indicates that a record set that must already exist
+// does not.
+type RecordSetNotFoundError struct {
+	Name string
+	Type dns.RecordType
+}
+
+func (e *RecordSetNotFoundError) Error() string {
+	return fmt.Sprintf("%v record set %v does not exist", e.Type, e.Name)
+}
+
+// ParseMetadata parses metadata entries given as KEY=VALUE. A value may be
+// empty, but a key may not, and each key may only be given once. Keys are
+// compared case-insensitively, as Azure does.
+func ParseMetadata(entries []string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, entry := range entries {
+		key, value, ok := splitMetadataEntry(entry)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid metadata %q must be of the form KEY=VALUE", entry)
+		}
+		if _, ok := lookupMetadata(metadata, key); ok {
+			return nil, fmt.Errorf("metadata key %q given more than once", key)
+		}
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
+
+// splitMetadataEntry splits a KEY=VALUE entry at the first equals sign.
+func splitMetadataEntry(entry string) (key string, value string, ok bool) {
+	i := strings.Index(entry, "=")
+	if i < 0 {
+		return entry, "", false
+	}
+
+	return entry[:i], entry[i+1:], true
+}
+
+// lookupMetadata returns the value in metadata for key, ignoring case.
+func lookupMetadata(metadata map[string]string, key string) (string, bool) {
+	if value, ok := metadata[key]; ok {
+		return value, true
+	}
+	for k, value := range metadata {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// RecordSetMetadata returns the metadata of rrset as a map, which is empty if
+// it has none.
+func RecordSetMetadata(rrset *dns.RecordSet) map[string]string {
+	if rrset == nil || rrset.RecordSetProperties == nil || rrset.Metadata == nil {
+		return map[string]string{}
+	}
+
+	return to.StringMap(*rrset.Metadata)
+}
+
+// UpdateMetadata returns a copy of current in which the entries in set have
+// been added or replaced and the keys in unset have been removed. Keys are
+// matched case-insensitively; a replaced entry takes the case of its new key.
+// If current is nil and there is nothing to set, the result is nil.
+func UpdateMetadata(current map[string]string, set map[string]string, unset []string) map[string]string {
+	if current == nil && len(set) == 0 {
+		return nil
+	}
+
+	result := map[string]string{}
+	for key, value := range current {
+		result[key] = value
+	}
+
+	remove := func(key string) {
+		for k := range result {
+			if strings.EqualFold(k, key) {
+				delete(result, k)
+			}
+		}
+	}
+
+	for _, key := range unset {
+		remove(key)
+	}
+	for key, value := range set {
+		remove(key)
+		result[key] = value
+	}
+
+	return result
+}
+
+// MetadataPointers converts metadata to the form used by the Azure SDK. A nil
+// map yields a nil pointer.
+func MetadataPointers(metadata map[string]string) *map[string]*string {
+	if metadata == nil {
+		return nil
+	}
+
+	result := map[string]*string{}
+	for key, value := range metadata {
+		result[key] = to.StringPtr(value)
+	}
+
+	return &result
+}
+
+// MetadataFilter selects record sets by their metadata. Each condition is
+// KEY, which requires the key to be present; KEY=VALUE, which requires it to
+// have the value; or !KEY, which requires it to be absent. Keys are matched
+// case-insensitively and values exactly.
+type MetadataFilter []metadataCondition
+
+type metadataCondition struct {
+	key    string
+	value  string
+	equals bool
+	absent bool
+}
+
+// ParseMetadataFilter parses the conditions of a MetadataFilter, all of which
+// must hold for a record set to match.
+func ParseMetadataFilter(conditions []string) (MetadataFilter, error) {
+	filter := MetadataFilter{}
+	for _, condition := range conditions {
+		var c metadataCondition
+		if strings.HasPrefix(condition, "!") {
+			c = metadataCondition{key: condition[1:], absent: true}
+		} else {
+			c.key, c.value, c.equals = splitMetadataEntry(condition)
+		}
+
+		if c.key == "" || (c.absent && strings.Contains(c.key, "=")) {
+			return nil, fmt.Errorf("invalid metadata filter %q must be of the form KEY, KEY=VALUE, or !KEY", condition)
+		}
+		filter = append(filter, c)
+	}
+
+	return filter, nil
+}
+
+// Matches reports whether the metadata of rrset satisfies every condition in
+// the filter.
+func (f MetadataFilter) Matches(rrset dns.RecordSet) bool {
+	metadata := RecordSetMetadata(&rrset)
+	for _, c := range f {
+		value, ok := lookupMetadata(metadata, c.key)
+		switch {
+		case c.absent:
+			if ok {
+				return false
+			}
+		case !ok:
+			return false
+		case c.equals && value != c.value:
+			return false
+		}
+	}
+
+	return true
+}
+
+// FormatMetadata returns metadata as KEY=VALUE entries sorted by key.
+func FormatMetadata(metadata map[string]string) []string {
+	entries := []string{}
+	for key, value := range metadata {
+		entries = append(entries, key+"="+value)
+	}
+	sort.Strings(entries)
+
+	return entries
+}
@@ -0,0 +1,156 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+type parseMetadataTestCase struct {
+	name     string
+	entries  []string
+	expected map[string]string
+	err      string
+}
+
+var parseMetadataTests = []parseMetadataTestCase{
+	{
+		name:     "entries",
+		entries:  []string{"owner=web", "note=a=b", "empty="},
+		expected: map[string]string{"owner": "web", "note": "a=b", "empty": ""},
+	},
+	{
+		name:     "none",
+		entries:  nil,
+		expected: map[string]string{},
+	},
+	{
+		name:    "no value",
+		entries: []string{"owner"},
+		err:     `invalid metadata "owner" must be of the form KEY=VALUE`,
+	},
+	{
+		name:    "no key",
+		entries: []string{"=web"},
+		err:     `invalid metadata "=web" must be of the form KEY=VALUE`,
+	},
+	{
+		name:    "duplicate",
+		entries: []string{"owner=web", "Owner=mail"},
+		err:     `metadata key "Owner" given more than once`,
+	},
+}
+
+func TestParseMetadata(t *testing.T) {
+	for _, testCase := range parseMetadataTests {
+		t.Run(testCase.name, func(t *testing.T) { testParseMetadata(t, testCase) })
+	}
+}
+
+func testParseMetadata(t *testing.T, testCase parseMetadataTestCase) {
+	metadata, err := ParseMetadata(testCase.entries)
+	if testCase.err != "" {
+		assert.EqualError(t, err, testCase.err)
+		return
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, testCase.expected, metadata)
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	current := map[string]string{"Owner": "web", "ticket": "OPS-1"}
+
+	assert.Equal(t,
+		map[string]string{"owner": "mail", "ticket": "OPS-1", "expires": "2027-01-01"},
+		UpdateMetadata(current, map[string]string{"owner": "mail", "expires": "2027-01-01"}, nil))
+	assert.Equal(t,
+		map[string]string{"Owner": "web"},
+		UpdateMetadata(current, nil, []string{"TICKET", "missing"}))
+	assert.Equal(t, map[string]string{}, UpdateMetadata(map[string]string{"owner": "web"}, nil, []string{"owner"}))
+	assert.Nil(t, UpdateMetadata(nil, nil, []string{"owner"}))
+	assert.Equal(t, map[string]string{"Owner": "web", "ticket": "OPS-1"}, current, "current should not be changed")
+}
+
+type metadataFilterTestCase struct {
+	name       string
+	conditions []string
+	expected   []string
+	err        string
+}
+
+var metadataFilterTests = []metadataFilterTestCase{
+	{
+		name:       "none",
+		conditions: nil,
+		expected:   []string{"www", "old", "bare"},
+	},
+	{
+		name:       "present",
+		conditions: []string{"OWNER"},
+		expected:   []string{"www", "old"},
+	},
+	{
+		name:       "value",
+		conditions: []string{"owner=mail"},
+		expected:   []string{"old"},
+	},
+	{
+		name:       "empty value",
+		conditions: []string{"owner="},
+		expected:   []string{},
+	},
+	{
+		name:       "absent",
+		conditions: []string{"!expires"},
+		expected:   []string{"www", "bare"},
+	},
+	{
+		name:       "all conditions",
+		conditions: []string{"owner", "!expires"},
+		expected:   []string{"www"},
+	},
+	{
+		name:       "no key",
+		conditions: []string{"=web"},
+		err:        `invalid metadata filter "=web" must be of the form KEY, KEY=VALUE, or !KEY`,
+	},
+	{
+		name:       "absent value",
+		conditions: []string{"!owner=web"},
+		err:        `invalid metadata filter "!owner=web" must be of the form KEY, KEY=VALUE, or !KEY`,
+	},
+}
+
+func TestMetadataFilter(t *testing.T) {
+	for _, testCase := range metadataFilterTests {
+		t.Run(testCase.name, func(t *testing.T) { testMetadataFilter(t, testCase) })
+	}
+}
+
+func testMetadataFilter(t *testing.T, testCase metadataFilterTestCase) {
+	www := newTestARecordSet("www", 300, "192.0.2.1")
+	www.Metadata = &map[string]*string{"Owner": to.StringPtr("web")}
+	old := newTestARecordSet("old", 300, "192.0.2.2")
+	old.Metadata = &map[string]*string{"owner": to.StringPtr("mail"), "expires": to.StringPtr("2020-01-01")}
+	bare := newTestARecordSet("bare", 300, "192.0.2.3")
+
+	filter, err := ParseMetadataFilter(testCase.conditions)
+	if testCase.err != "" {
+		assert.EqualError(t, err, testCase.err)
+		return
+	}
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	matched := []string{}
+	for _, rrset := range []dns.RecordSet{www, old, bare} {
+		if filter.Matches(rrset) {
+			matched = append(matched, to.String(rrset.Name))
+		}
+	}
+	assert.Equal(t, testCase.expected, matched)
+}
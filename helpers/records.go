@@ -0,0 +1,190 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// RecordSetType determines the record type of a record set returned by Azure.
+// Azure reports the type as a resource type, such as
+// "Microsoft.Network/dnszones/A", so everything up to the final slash is
+// discarded.
+func RecordSetType(rrset dns.RecordSet) dns.RecordType {
+	resourceType := to.String(rrset.Type)
+	if i := strings.LastIndex(resourceType, "/"); i >= 0 {
+		resourceType = resourceType[i+1:]
+	}
+
+	return dns.RecordType(strings.ToUpper(resourceType))
+}
+
+// RecordValues returns the records of the given type contained in props, each
+// formatted as the RDATA portion of a line in an RFC 1035 zone file. Character
+// strings, such as those in TXT and CAA records, are quoted. Domain names are
+// printed exactly as Azure stores them.
+func RecordValues(recordType dns.RecordType, props *dns.RecordSetProperties) []string {
+	return recordValues(recordType, props, false)
+}
+
+// ZoneFileValues is like RecordValues, but the values are suitable for use in a
+// zone file: domain names are made fully-qualified, because Azure treats them
+// as absolute whether or not they end with a dot, and TXT strings longer than
+// 255 bytes are split into multiple character strings.
+func ZoneFileValues(recordType dns.RecordType, props *dns.RecordSetProperties) []string {
+	return recordValues(recordType, props, true)
+}
+
+func recordValues(recordType dns.RecordType, props *dns.RecordSetProperties, zoneFile bool) []string {
+	name := func(value *string) string {
+		if zoneFile {
+			return Fqdn(to.String(value))
+		}
+		return to.String(value)
+	}
+
+	values := []string{}
+	if props == nil {
+		return values
+	}
+
+	switch recordType {
+	case dns.A:
+		if props.ARecords != nil {
+			for _, record := range *props.ARecords {
+				values = append(values, to.String(record.Ipv4Address))
+			}
+		}
+	case dns.AAAA:
+		if props.AaaaRecords != nil {
+			for _, record := range *props.AaaaRecords {
+				values = append(values, to.String(record.Ipv6Address))
+			}
+		}
+	case dns.CAA:
+		if props.CaaRecords != nil {
+			for _, record := range *props.CaaRecords {
+				values = append(values, fmt.Sprintf("%v %v %v", to.Int32(record.Flags), to.String(record.Tag),
+					QuoteCharacterString(to.String(record.Value))))
+			}
+		}
+	case dns.CNAME:
+		if props.CnameRecord != nil {
+			values = append(values, name(props.CnameRecord.Cname))
+		}
+	case dns.MX:
+		if props.MxRecords != nil {
+			for _, record := range *props.MxRecords {
+				values = append(values, fmt.Sprintf("%v %v", to.Int32(record.Preference), name(record.Exchange)))
+			}
+		}
+	case dns.NS:
+		if props.NsRecords != nil {
+			for _, record := range *props.NsRecords {
+				values = append(values, name(record.Nsdname))
+			}
+		}
+	case dns.PTR:
+		if props.PtrRecords != nil {
+			for _, record := range *props.PtrRecords {
+				values = append(values, name(record.Ptrdname))
+			}
+		}
+	case dns.SOA:
+		if props.SoaRecord != nil {
+			record := props.SoaRecord
+			values = append(values, fmt.Sprintf("%v %v %v %v %v %v %v", name(record.Host), name(record.Email),
+				to.Int64(record.SerialNumber), to.Int64(record.RefreshTime), to.Int64(record.RetryTime),
+				to.Int64(record.ExpireTime), to.Int64(record.MinimumTTL)))
+		}
+	case dns.SRV:
+		if props.SrvRecords != nil {
+			for _, record := range *props.SrvRecords {
+				values = append(values, fmt.Sprintf("%v %v %v %v", to.Int32(record.Priority), to.Int32(record.Weight),
+					to.Int32(record.Port), name(record.Target)))
+			}
+		}
+	case dns.TXT:
+		if props.TxtRecords != nil {
+			for _, record := range *props.TxtRecords {
+				quoted := []string{}
+				for _, value := range to.StringSlice(record.Value) {
+					chunks := []string{value}
+					if zoneFile {
+						chunks = SplitCharacterString(value)
+					}
+					for _, chunk := range chunks {
+						quoted = append(quoted, QuoteCharacterString(chunk))
+					}
+				}
+				values = append(values, strings.Join(quoted, " "))
+			}
+		}
+	}
+
+	return values
+}
+
+// MaxCharacterStringLength is the maximum length, in bytes, of an RFC 1035
+// <character-string>.
+const MaxCharacterStringLength = 255
+
+// SplitCharacterString splits value into pieces no longer than
+// MaxCharacterStringLength bytes, each of which may be written as a single
+// <character-string>. Pieces end between UTF-8 sequences where possible, so
+// that each is valid UTF-8 if value is; concatenating them gives value. The
+// empty string produces a single, empty piece.
+func SplitCharacterString(value string) []string {
+	chunks := []string{}
+	for len(value) > MaxCharacterStringLength {
+		end := MaxCharacterStringLength
+		for end > 0 && !utf8.RuneStart(value[end]) {
+			end--
+		}
+		if end == 0 {
+			end = MaxCharacterStringLength
+		}
+		chunks = append(chunks, value[:end])
+		value = value[end:]
+	}
+
+	return append(chunks, value)
+}
+
+// Fqdn makes name fully-qualified by adding a trailing dot if it does not
+// already have one.
+func Fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+
+	return name + "."
+}
+
+// QuoteCharacterString formats value as an RFC 1035 <character-string>,
+// surrounded by double quotes. Double quotes and backslashes are escaped with a
+// backslash, and bytes outside of printable ASCII are written as \DDD decimal
+// escapes.
+func QuoteCharacterString(value string) string {
+	var quoted strings.Builder
+
+	quoted.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&quoted, "\\%03d", c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+
+	return quoted.String()
+}
@@ -0,0 +1,148 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordSetTypeTestCase struct {
+	resourceType string
+	expectedType dns.RecordType
+}
+
+var recordSetTypeTests = []recordSetTypeTestCase{
+	{"Microsoft.Network/dnszones/A", dns.A},
+	{"Microsoft.Network/dnszones/AAAA", dns.AAAA},
+	{"Microsoft.Network/dnszones/txt", dns.TXT},
+	{"CNAME", dns.CNAME},
+	{"", ""},
+}
+
+func TestRecordSetType(t *testing.T) {
+	for _, testCase := range recordSetTypeTests {
+		t.Run(testCase.resourceType, func(t *testing.T) { testRecordSetType(t, testCase) })
+	}
+}
+
+func testRecordSetType(t *testing.T, testCase recordSetTypeTestCase) {
+	rrset := dns.RecordSet{Type: to.StringPtr(testCase.resourceType)}
+	assert.Equal(t, testCase.expectedType, RecordSetType(rrset))
+}
+
+type recordValuesTestCase struct {
+	name           string
+	recordType     dns.RecordType
+	props          *dns.RecordSetProperties
+	expectedValues []string
+}
+
+var recordValuesTests = []recordValuesTestCase{
+	{"A", dns.A, &dns.RecordSetProperties{
+		ARecords: &[]dns.ARecord{{Ipv4Address: to.StringPtr("1.1.1.1")}, {Ipv4Address: to.StringPtr("2.2.2.2")}},
+	}, []string{"1.1.1.1", "2.2.2.2"}},
+	{"AAAA", dns.AAAA, &dns.RecordSetProperties{
+		AaaaRecords: &[]dns.AaaaRecord{{Ipv6Address: to.StringPtr("::1")}},
+	}, []string{"::1"}},
+	{"CAA", dns.CAA, &dns.RecordSetProperties{
+		CaaRecords: &[]dns.CaaRecord{{Flags: to.Int32Ptr(0), Tag: to.StringPtr("issue"), Value: to.StringPtr("letsencrypt.org")}},
+	}, []string{`0 issue "letsencrypt.org"`}},
+	{"CNAME", dns.CNAME, &dns.RecordSetProperties{
+		CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.com")},
+	}, []string{"example.com"}},
+	{"MX", dns.MX, &dns.RecordSetProperties{
+		MxRecords: &[]dns.MxRecord{{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("mail.example.com")}},
+	}, []string{"10 mail.example.com"}},
+	{"NS", dns.NS, &dns.RecordSetProperties{
+		NsRecords: &[]dns.NsRecord{{Nsdname: to.StringPtr("ns1.example.com")}},
+	}, []string{"ns1.example.com"}},
+	{"PTR", dns.PTR, &dns.RecordSetProperties{
+		PtrRecords: &[]dns.PtrRecord{{Ptrdname: to.StringPtr("host.example.com")}},
+	}, []string{"host.example.com"}},
+	{"SOA", dns.SOA, &dns.RecordSetProperties{
+		SoaRecord: &dns.SoaRecord{
+			Host:         to.StringPtr("ns1.example.com"),
+			Email:        to.StringPtr("hostmaster.example.com"),
+			SerialNumber: to.Int64Ptr(1),
+			RefreshTime:  to.Int64Ptr(3600),
+			RetryTime:    to.Int64Ptr(300),
+			ExpireTime:   to.Int64Ptr(2419200),
+			MinimumTTL:   to.Int64Ptr(300),
+		},
+	}, []string{"ns1.example.com hostmaster.example.com 1 3600 300 2419200 300"}},
+	{"SRV", dns.SRV, &dns.RecordSetProperties{
+		SrvRecords: &[]dns.SrvRecord{{
+			Priority: to.Int32Ptr(10),
+			Weight:   to.Int32Ptr(60),
+			Port:     to.Int32Ptr(5060),
+			Target:   to.StringPtr("sip.example.com"),
+		}},
+	}, []string{"10 60 5060 sip.example.com"}},
+	{"TXT", dns.TXT, &dns.RecordSetProperties{
+		TxtRecords: &[]dns.TxtRecord{
+			{Value: &[]string{"v=spf1 -all"}},
+			{Value: &[]string{"first", `"second"`}},
+		},
+	}, []string{`"v=spf1 -all"`, `"first" "\"second\""`}},
+	{"missing records", dns.A, &dns.RecordSetProperties{}, []string{}},
+	{"missing properties", dns.A, nil, []string{}},
+}
+
+func TestRecordValues(t *testing.T) {
+	for _, testCase := range recordValuesTests {
+		t.Run(testCase.name, func(t *testing.T) { testRecordValues(t, testCase) })
+	}
+}
+
+func testRecordValues(t *testing.T, testCase recordValuesTestCase) {
+	result := RecordValues(testCase.recordType, testCase.props)
+	assert.Equal(t, testCase.expectedValues, result)
+}
+
+type quoteCharacterStringTestCase struct {
+	value          string
+	expectedResult string
+}
+
+var quoteCharacterStringTests = []quoteCharacterStringTestCase{
+	{"", `""`},
+	{"simple", `"simple"`},
+	{"with spaces", `"with spaces"`},
+	{`quote"d`, `"quote\"d"`},
+	{`back\slash`, `"back\\slash"`},
+	{"semi;colon", `"semi;colon"`},
+	{"tab\there", `"tab\009here"`},
+	{"café", `"caf\195\169"`},
+}
+
+func TestQuoteCharacterString(t *testing.T) {
+	for _, testCase := range quoteCharacterStringTests {
+		t.Run(testCase.value, func(t *testing.T) { testQuoteCharacterString(t, testCase) })
+	}
+}
+
+func testQuoteCharacterString(t *testing.T, testCase quoteCharacterStringTestCase) {
+	assert.Equal(t, testCase.expectedResult, QuoteCharacterString(testCase.value))
+}
+
+func TestSplitCharacterString(t *testing.T) {
+	long := strings.Repeat("x", 600)
+
+	assert.Equal(t, []string{""}, SplitCharacterString(""))
+	assert.Equal(t, []string{"short"}, SplitCharacterString("short"))
+	assert.Equal(t, []string{long[:255]}, SplitCharacterString(long[:255]))
+	assert.Equal(t, []string{long[:255], long[255:510], long[510:]}, SplitCharacterString(long))
+
+	// A multi-byte character that would straddle the limit starts the next
+	// piece instead.
+	accented := long[:254] + "éé"
+	assert.Equal(t, []string{long[:254], "éé"}, SplitCharacterString(accented))
+}
+
+func TestFqdn(t *testing.T) {
+	assert.Equal(t, "example.com.", Fqdn("example.com"))
+	assert.Equal(t, "example.com.", Fqdn("example.com."))
+}
@@ -0,0 +1,375 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/spf13/viper"
+)
+
+// Defaults for the retry policy used by API clients.
+const (
+	// DefaultMaxRetries is the number of times a request that fails
+	// transiently is retried, unless told otherwise.
+	DefaultMaxRetries = 3
+	// DefaultRetryDeadline limits the total time spent on a request, including
+	// retries, unless told otherwise.
+	DefaultRetryDeadline = 2 * time.Minute
+)
+
+// RetryBackoff is the delay before the first retry of a request that failed
+// without a Retry-After header. It doubles with each subsequent retry, up to
+// MaxRetryBackoff.
+var RetryBackoff = time.Second
+
+// MaxRetryBackoff limits the delay between retries when the server does not
+// give one.
+var MaxRetryBackoff = 30 * time.Second
+
+// LowRateLimit is the number of remaining requests, as reported by the
+// x-ms-ratelimit-remaining-* headers, below which requests are slowed down to
+// avoid being throttled.
+const LowRateLimit = 10
+
+// retryStatusCodes are the HTTP status codes that indicate a transient failure.
+var retryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// disableSDKRetries turns off the status code retries of the SDK, which wraps
+// every request in its own retry loop that retries throttled requests forever
+// with fixed delays of 30 seconds or more. The SDK only reads the status codes
+// from autorest.StatusCodesForRetry, so this is done the first time a client
+// is made to use a RetryPolicy rather than when the package is loaded.
+var disableSDKRetries sync.Once
+
+// RetryPolicy controls how requests to Azure Resource Manager are retried
+// after transient failures: connection errors, throttling (429), and server
+// errors.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried.
+	MaxRetries int
+	// Deadline limits the total time spent on a request, including retries
+	// and the delays between them. There is no limit if it is zero.
+	Deadline time.Duration
+	// Logf, if not nil, is called to describe each retry.
+	Logf func(format string, args ...interface{})
+}
+
+// GetRetryPolicy returns the retry policy configured with the max-retries and
+// retry-deadline options. Retries are logged to stderr if the verbose option
+// is set.
+func GetRetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries: viper.GetInt("max-retries"),
+		Deadline:   viper.GetDuration("retry-deadline"),
+	}
+
+	if viper.GetBool("verbose") {
+		policy.Logf = func(format string, args ...interface{}) {
+			fmt.Fprintf(os.Stderr, format+"\n", args...)
+		}
+	}
+
+	return policy
+}
+
+// Apply makes client send its requests through the policy. Since the SDK's
+// retries cannot be configured per client, this also disables its status code
+// retries for every client; clients that do not use a RetryPolicy then retry
+// only connection errors.
+func (policy RetryPolicy) Apply(client *autorest.Client) {
+	disableSDKRetries.Do(func() { autorest.StatusCodesForRetry = nil })
+
+	client.Sender = NewRetrySender(client.Sender, policy)
+	// The SDK still retries connection errors itself, RetryAttempts times
+	// and with a backoff of RetryDuration. Since the policy has already done
+	// so, keep that to a single immediate retry. The sender answers it with
+	// the original error for writes it would not retry.
+	client.RetryAttempts = 1
+	client.RetryDuration = 0
+}
+
+// retrySender is a Sender that retries requests according to a RetryPolicy.
+// It is safe for concurrent use.
+type retrySender struct {
+	sender autorest.Sender
+	policy RetryPolicy
+
+	mu sync.Mutex
+	// pauseUntil delays further requests after a response indicated that
+	// few requests remain before throttling begins.
+	pauseUntil time.Time
+	// failedWrites holds the errors of writes that failed in a way that may
+	// have left them applied, until the SDK sends them again.
+	failedWrites map[*http.Request]error
+}
+
+// NewRetrySender returns a Sender that sends requests with sender, retrying
+// them according to policy. The delay before each retry is taken from the
+// response's Retry-After header if it has one, and otherwise grows
+// exponentially. Writes are only retried if they were throttled or are
+// conditional on an etag, since a write that failed otherwise may have been
+// applied, and sending it again could overwrite a later change. When
+// responses report through the x-ms-ratelimit-remaining-*
+// headers that fewer than LowRateLimit requests remain, subsequent requests
+// are delayed so as not to be throttled. If sender is nil, a new http.Client
+// is used.
+func NewRetrySender(sender autorest.Sender, policy RetryPolicy) autorest.Sender {
+	if sender == nil {
+		sender = &http.Client{}
+	}
+
+	return &retrySender{sender: sender, policy: policy, failedWrites: map[*http.Request]error{}}
+}
+
+// Do sends r, retrying as necessary.
+func (s *retrySender) Do(r *http.Request) (*http.Response, error) {
+	replayable := canReplay(r)
+	if replayable {
+		return s.send(r, true)
+	}
+
+	// The SDK sends a request again after a connection error, which must not
+	// happen to a write that may have been applied.
+	s.mu.Lock()
+	err, failed := s.failedWrites[r]
+	delete(s.failedWrites, r)
+	s.mu.Unlock()
+	if failed {
+		return nil, err
+	}
+
+	resp, err := s.send(r, false)
+	if err != nil {
+		s.mu.Lock()
+		s.failedWrites[r] = err
+		s.mu.Unlock()
+	}
+	return resp, err
+}
+
+// send sends r, retrying as necessary. Unless replayable, r is only retried
+// if it was throttled.
+func (s *retrySender) send(r *http.Request, replayable bool) (*http.Response, error) {
+	ctx := r.Context()
+	var deadline time.Time
+	if s.policy.Deadline > 0 {
+		deadline = time.Now().Add(s.policy.Deadline)
+	}
+
+	rr := autorest.NewRetriableRequest(r)
+	for attempt := 0; ; attempt++ {
+		if err := s.pause(r); err != nil {
+			return nil, err
+		}
+
+		if err := rr.Prepare(); err != nil {
+			return nil, err
+		}
+
+		resp, err := s.sender.Do(rr.Request())
+		s.checkRateLimit(resp)
+
+		if ctx.Err() != nil || !isRetriable(resp, err) || attempt >= s.policy.MaxRetries {
+			return resp, err
+		}
+
+		if !replayable && (resp == nil || resp.StatusCode != http.StatusTooManyRequests) {
+			s.logf("not retrying %v %v, since it may have been applied", r.Method, r.URL.Path)
+			return resp, err
+		}
+
+		delay := retryDelay(resp, attempt)
+		if !deadline.IsZero() && time.Now().Add(delay).After(deadline) {
+			return resp, err
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			drainResponse(resp)
+		}
+		s.logf("retrying %v %v in %v after %v (retry %v of %v)", r.Method, r.URL.Path, delay, reason, attempt+1, s.policy.MaxRetries)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// pause waits until requests may be sent again after the rate limit ran low.
+func (s *retrySender) pause(r *http.Request) error {
+	s.mu.Lock()
+	delay := time.Until(s.pauseUntil)
+	s.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	s.logf("few requests remain before throttling; waiting %v before %v %v", delay.Round(time.Millisecond), r.Method, r.URL.Path)
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-r.Context().Done():
+		return r.Context().Err()
+	}
+}
+
+// checkRateLimit delays further requests if resp reports that few requests
+// remain before throttling: by RetryBackoff if some remain, or by
+// MaxRetryBackoff if none do.
+func (s *retrySender) checkRateLimit(resp *http.Response) {
+	remaining, ok := RemainingRateLimit(resp)
+	if !ok || remaining >= LowRateLimit {
+		return
+	}
+
+	delay := RetryBackoff
+	if remaining <= 0 {
+		delay = MaxRetryBackoff
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(s.pauseUntil) {
+		s.pauseUntil = until
+	}
+}
+
+func (s *retrySender) logf(format string, args ...interface{}) {
+	if s.policy.Logf != nil {
+		s.policy.Logf(format, args...)
+	}
+}
+
+// isRetriable reports whether a request that produced resp and err failed
+// transiently.
+func isRetriable(resp *http.Response, err error) bool {
+	if err != nil {
+		return resp == nil
+	}
+
+	for _, code := range retryStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canReplay reports whether r may be sent again after failing in a way that
+// may have left it applied: it is a read, or a write made conditional on an
+// etag with If-Match or If-None-Match, which fails if it was applied before.
+func canReplay(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return r.Header.Get("If-Match") != "" || r.Header.Get("If-None-Match") != ""
+}
+
+// retryDelay returns how long to wait before retrying a request that failed
+// with resp, which may be nil, on the given zero-based attempt.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if delay, ok := RetryAfter(resp, time.Now()); ok {
+		return delay
+	}
+
+	delay := RetryBackoff << uint(attempt)
+	if delay <= 0 || delay > MaxRetryBackoff {
+		delay = MaxRetryBackoff
+	}
+
+	// Spread out the retries of concurrent requests.
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// RetryAfter returns the delay requested by the server in resp, which may be
+// nil, through the Retry-After header, given in seconds or as an HTTP date
+// relative to now, or the retry-after-ms or x-ms-retry-after-ms headers. If
+// there is no such delay, false is returned.
+func RetryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	for _, header := range []string{"Retry-After-Ms", "X-Ms-Retry-After-Ms"} {
+		if ms, err := strconv.ParseInt(resp.Header.Get(header), 10, 64); err == nil && ms >= 0 {
+			return time.Duration(ms) * time.Millisecond, true
+		}
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// RemainingRateLimit returns the smallest number of remaining requests
+// reported by the x-ms-ratelimit-remaining-* headers of resp, which may be
+// nil, or false if there are none.
+func RemainingRateLimit(resp *http.Response) (int, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	remaining, found := 0, false
+	for header, values := range resp.Header {
+		if !strings.HasPrefix(strings.ToLower(header), "x-ms-ratelimit-remaining-") || len(values) == 0 {
+			continue
+		}
+
+		value, err := strconv.Atoi(values[0])
+		if err != nil {
+			continue
+		}
+
+		if !found || value < remaining {
+			remaining, found = value, true
+		}
+	}
+
+	return remaining, found
+}
+
+// drainResponse discards the body of a response that will not be used, so
+// that its connection can be reused.
+func drainResponse(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
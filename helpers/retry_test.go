@@ -0,0 +1,277 @@
+package helpers
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/stretchr/testify/assert"
+)
+
+type retryAfterTestCase struct {
+	name    string
+	headers map[string]string
+	delay   time.Duration
+	ok      bool
+}
+
+var retryAfterNow = time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+var retryAfterTests = []retryAfterTestCase{
+	{"none", map[string]string{}, 0, false},
+	{"seconds", map[string]string{"Retry-After": "5"}, 5 * time.Second, true},
+	{"date", map[string]string{"Retry-After": "Fri, 01 Jun 2018 12:00:10 GMT"}, 10 * time.Second, true},
+	{"past date", map[string]string{"Retry-After": "Fri, 01 Jun 2018 11:00:00 GMT"}, 0, true},
+	{"milliseconds", map[string]string{"Retry-After": "5", "Retry-After-Ms": "1500"}, 1500 * time.Millisecond, true},
+	{"ms milliseconds", map[string]string{"X-Ms-Retry-After-Ms": "250"}, 250 * time.Millisecond, true},
+	{"invalid", map[string]string{"Retry-After": "soon"}, 0, false},
+	{"negative", map[string]string{"Retry-After": "-1"}, 0, false},
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, testCase := range retryAfterTests {
+		t.Run(testCase.name, func(t *testing.T) { testRetryAfter(t, testCase) })
+	}
+
+	_, ok := RetryAfter(nil, retryAfterNow)
+	assert.False(t, ok)
+}
+
+func testRetryAfter(t *testing.T, testCase retryAfterTestCase) {
+	resp := &http.Response{Header: http.Header{}}
+	for header, value := range testCase.headers {
+		resp.Header.Set(header, value)
+	}
+
+	delay, ok := RetryAfter(resp, retryAfterNow)
+	assert.Equal(t, testCase.ok, ok)
+	assert.Equal(t, testCase.delay, delay)
+}
+
+func TestRemainingRateLimit(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := RemainingRateLimit(resp)
+	assert.False(t, ok)
+
+	resp.Header.Set("x-ms-ratelimit-remaining-subscription-reads", "11999")
+	resp.Header.Set("x-ms-ratelimit-remaining-subscription-writes", "42")
+	resp.Header.Set("x-ms-ratelimit-remaining-resource", "Microsoft.Network/dnszones;10")
+	remaining, ok := RemainingRateLimit(resp)
+	assert.True(t, ok)
+	assert.Equal(t, 42, remaining)
+}
+
+// flakyServer fails each request with the next of its status codes, then
+// succeeds, recording the bodies of the requests it receives.
+type flakyServer struct {
+	mu       sync.Mutex
+	failures []int
+	headers  http.Header
+	bodies   []string
+}
+
+func (f *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	f.bodies = append(f.bodies, string(body))
+
+	for header, values := range f.headers {
+		w.Header()[header] = values
+	}
+
+	if len(f.failures) > 0 {
+		w.WriteHeader(f.failures[0])
+		f.failures = f.failures[1:]
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func sendWithRetries(t *testing.T, fake *flakyServer, policy RetryPolicy, method string, header http.Header) (*http.Response, error) {
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	oldBackoff := RetryBackoff
+	RetryBackoff = time.Millisecond
+	defer func() { RetryBackoff = oldBackoff }()
+
+	req, err := http.NewRequest(method, server.URL, strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := NewRetrySender(nil, policy).Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func TestRetrySender(t *testing.T) {
+	logged := 0
+	policy := RetryPolicy{
+		MaxRetries: 3,
+		Logf:       func(string, ...interface{}) { logged++ },
+	}
+
+	fake := &flakyServer{failures: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	resp, err := sendWithRetries(t, fake, policy, http.MethodGet, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Equal(t, []string{"body", "body", "body"}, fake.bodies)
+	assert.Equal(t, 2, logged)
+
+	// Requests are retried at most MaxRetries times.
+	fake = &flakyServer{failures: []int{500, 502, 503, 504, 408}}
+	resp, err = sendWithRetries(t, fake, policy, http.MethodGet, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	}
+	assert.Len(t, fake.bodies, 4)
+
+	// Other failures are not retried.
+	fake = &flakyServer{failures: []int{http.StatusBadRequest}}
+	resp, err = sendWithRetries(t, fake, policy, http.MethodGet, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+	assert.Len(t, fake.bodies, 1)
+}
+
+type retryWriteTestCase struct {
+	name     string
+	method   string
+	header   http.Header
+	failures []int
+	sends    int
+	status   int
+}
+
+var retryWriteTests = []retryWriteTestCase{
+	{"read after server error", http.MethodGet, nil, []int{http.StatusServiceUnavailable}, 2, http.StatusOK},
+	{"write after throttling", http.MethodPut, nil, []int{http.StatusTooManyRequests}, 2, http.StatusOK},
+	{"write after server error", http.MethodPut, nil, []int{http.StatusServiceUnavailable}, 1, http.StatusServiceUnavailable},
+	{"delete after server error", http.MethodDelete, nil, []int{http.StatusInternalServerError}, 1, http.StatusInternalServerError},
+	{"write with If-Match", http.MethodPut, http.Header{"If-Match": []string{"etag"}}, []int{http.StatusBadGateway}, 2, http.StatusOK},
+	{"write with If-None-Match", http.MethodPut, http.Header{"If-None-Match": []string{"*"}}, []int{http.StatusGatewayTimeout}, 2, http.StatusOK},
+}
+
+func TestRetrySenderWrites(t *testing.T) {
+	for _, testCase := range retryWriteTests {
+		t.Run(testCase.name, func(t *testing.T) { testRetrySenderWrites(t, testCase) })
+	}
+}
+
+func testRetrySenderWrites(t *testing.T, testCase retryWriteTestCase) {
+	fake := &flakyServer{failures: testCase.failures}
+	resp, err := sendWithRetries(t, fake, RetryPolicy{MaxRetries: 3}, testCase.method, testCase.header)
+	if assert.NoError(t, err) {
+		assert.Equal(t, testCase.status, resp.StatusCode)
+	}
+	assert.Len(t, fake.bodies, testCase.sends)
+}
+
+func TestRetrySenderSDKReplay(t *testing.T) {
+	oldBackoff := RetryBackoff
+	RetryBackoff = time.Millisecond
+	defer func() { RetryBackoff = oldBackoff }()
+
+	sends := 0
+	failing := autorest.SenderFunc(func(r *http.Request) (*http.Response, error) {
+		sends++
+		return nil, errors.New("connection reset by peer")
+	})
+	sender := NewRetrySender(failing, RetryPolicy{MaxRetries: 1})
+
+	// The SDK retries connection errors once, as configured by Apply.
+	for method, expected := range map[string]int{http.MethodGet: 4, http.MethodPut: 1} {
+		sends = 0
+		req, err := http.NewRequest(method, "https://management.azure.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = autorest.SendWithSender(sender, req, autorest.DoRetryForStatusCodes(1, 0))
+		assert.Error(t, err)
+		assert.Equal(t, expected, sends, method)
+	}
+	assert.Empty(t, sender.(*retrySender).failedWrites)
+}
+
+func TestRetrySenderDeadline(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, Deadline: time.Second}
+
+	// A Retry-After beyond the deadline is not waited for.
+	fake := &flakyServer{
+		failures: []int{http.StatusTooManyRequests},
+		headers:  http.Header{"Retry-After": []string{"60"}},
+	}
+	start := time.Now()
+	resp, err := sendWithRetries(t, fake, policy, http.MethodGet, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	}
+	assert.Len(t, fake.bodies, 1)
+	assert.True(t, time.Since(start) < 30*time.Second)
+
+	fake = &flakyServer{
+		failures: []int{http.StatusTooManyRequests},
+		headers:  http.Header{"Retry-After": []string{"0"}},
+	}
+	resp, err = sendWithRetries(t, fake, policy, http.MethodGet, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Len(t, fake.bodies, 2)
+}
+
+func TestRetrySenderRateLimit(t *testing.T) {
+	fake := &flakyServer{headers: http.Header{"X-Ms-Ratelimit-Remaining-Subscription-Writes": []string{"1"}}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	oldBackoff := RetryBackoff
+	RetryBackoff = 50 * time.Millisecond
+	defer func() { RetryBackoff = oldBackoff }()
+
+	sender := NewRetrySender(nil, RetryPolicy{})
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+		resp, err := sender.Do(req)
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+
+		// Only the second request is slowed down.
+		assert.Equal(t, i > 0, time.Since(start) >= RetryBackoff)
+	}
+}
+
+func TestRetryPolicyApply(t *testing.T) {
+	client := autorest.NewClientWithUserAgent("")
+	RetryPolicy{MaxRetries: 2}.Apply(&client)
+
+	if sender, ok := client.Sender.(*retrySender); assert.True(t, ok) {
+		assert.Equal(t, 2, sender.policy.MaxRetries)
+	}
+	assert.Equal(t, 1, client.RetryAttempts)
+	assert.Empty(t, autorest.StatusCodesForRetry)
+}
@@ -0,0 +1,46 @@
+package helpers
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// TTLLimits bounds the TTLs that may be given to record sets. A limit of zero
+// means that there is none.
+type TTLLimits struct {
+	Min int64
+	Max int64
+}
+
+// GetTTLLimits returns the limits set with the min-ttl and max-ttl options,
+// which are usually given as per-zone defaults in the config file.
+func GetTTLLimits() TTLLimits {
+	return TTLLimits{
+		Min: viper.GetInt64("min-ttl"),
+		Max: viper.GetInt64("max-ttl"),
+	}
+}
+
+// Check returns a *TTLLimitError if ttl is outside the limits.
+func (l TTLLimits) Check(ttl int64) error {
+	if (l.Min > 0 && ttl < l.Min) || (l.Max > 0 && ttl > l.Max) {
+		return &TTLLimitError{TTL: ttl, Limits: l}
+	}
+
+	return nil
+}
+
+// TTLLimitError indicates that a TTL is outside the limits for its zone.
+type TTLLimitError struct {
+	TTL    int64
+	Limits TTLLimits
+}
+
+func (e *TTLLimitError) Error() string {
+	if e.Limits.Min > 0 && e.TTL < e.Limits.Min {
+		return fmt.Sprintf("TTL %v is below the minimum of %v for this zone", e.TTL, e.Limits.Min)
+	}
+
+	return fmt.Sprintf("TTL %v is above the maximum of %v for this zone", e.TTL, e.Limits.Max)
+}
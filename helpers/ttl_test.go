@@ -0,0 +1,61 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ttlLimitsTestCase struct {
+	name   string
+	limits TTLLimits
+	ttl    int64
+	err    string
+}
+
+var ttlLimitsTests = []ttlLimitsTestCase{
+	{
+		name:   "no limits",
+		limits: TTLLimits{},
+		ttl:    1,
+	},
+	{
+		name:   "within",
+		limits: TTLLimits{Min: 60, Max: 86400},
+		ttl:    3600,
+	},
+	{
+		name:   "at limits",
+		limits: TTLLimits{Min: 60, Max: 60},
+		ttl:    60,
+	},
+	{
+		name:   "below",
+		limits: TTLLimits{Min: 60},
+		ttl:    30,
+		err:    "TTL 30 is below the minimum of 60 for this zone",
+	},
+	{
+		name:   "above",
+		limits: TTLLimits{Min: 60, Max: 86400},
+		ttl:    604800,
+		err:    "TTL 604800 is above the maximum of 86400 for this zone",
+	},
+}
+
+func TestTTLLimits(t *testing.T) {
+	for _, testCase := range ttlLimitsTests {
+		t.Run(testCase.name, func(t *testing.T) { testTTLLimits(t, testCase) })
+	}
+}
+
+func testTTLLimits(t *testing.T, testCase ttlLimitsTestCase) {
+	err := testCase.limits.Check(testCase.ttl)
+	if testCase.err == "" {
+		assert.NoError(t, err)
+		return
+	}
+
+	assert.EqualError(t, err, testCase.err)
+	assert.IsType(t, &TTLLimitError{}, err)
+}
@@ -0,0 +1,321 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+const (
+	// MaxLabelLength is the longest a label of a domain name may be, in bytes.
+	MaxLabelLength = 63
+	// MaxNameLength is the longest a domain name may be, in bytes, when
+	// written without its trailing dot.
+	MaxNameLength = 253
+	// MaxCAATagLength is the longest a CAA property tag may be, in bytes.
+	MaxCAATagLength = 15
+)
+
+// ValidateDomainName checks that name is a syntactically valid domain name,
+// such as the target of a CNAME, NS, or PTR record. name may be absolute or
+// relative. Labels may contain letters, digits, hyphens, and underscores,
+// which are common in names such as _domainkey records, and must be between 1
+// and 63 bytes long.
+func ValidateDomainName(name string) error {
+	return validateName(name, false, false)
+}
+
+// ValidateHostname checks that name is a valid host name (RFC 1123, section
+// 2.1), as required for the targets of MX and SRV records: labels may only
+// contain letters, digits, and hyphens, and may not begin or end with a hyphen.
+func ValidateHostname(name string) error {
+	return validateName(name, true, false)
+}
+
+// ValidateRecordName checks that name, relative to its zone, is a valid name
+// for a record set. The apex is written as @, and the leftmost label may be *
+// to create a wildcard record set.
+func ValidateRecordName(name string) error {
+	if name == "@" {
+		return nil
+	}
+
+	return validateName(name, false, true)
+}
+
+// validateName implements ValidateDomainName, ValidateHostname, and
+// ValidateRecordName. If strict is true, labels must follow the rules for host
+// names. If wildcard is true, the leftmost label may be *.
+func validateName(name string, strict bool, wildcard bool) error {
+	trimmed := strings.TrimSuffix(name, ".")
+	if trimmed == "" {
+		return fmt.Errorf("invalid name %q must not be empty", name)
+	}
+	if len(trimmed) > MaxNameLength {
+		return fmt.Errorf("invalid name %q is %v bytes long; the limit is %v", name, len(trimmed), MaxNameLength)
+	}
+
+	for i, label := range strings.Split(trimmed, ".") {
+		if err := validateLabel(label, strict, wildcard && i == 0); err != nil {
+			return fmt.Errorf("invalid name %q: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateLabel checks a single label of a domain name for validateName.
+func validateLabel(label string, strict bool, wildcard bool) error {
+	if label == "" {
+		return fmt.Errorf("labels must not be empty")
+	}
+	if len(label) > MaxLabelLength {
+		return fmt.Errorf("label %q is %v bytes long; the limit is %v", label, len(label), MaxLabelLength)
+	}
+	if wildcard && label == "*" {
+		return nil
+	}
+
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-':
+		case c == '_' && !strict:
+		case c >= utf8.RuneSelf:
+			return fmt.Errorf("label %q contains %q; internationalized names must be given in their ASCII (xn--) form", label, c)
+		default:
+			return fmt.Errorf("label %q contains invalid character %q", label, c)
+		}
+	}
+
+	if strict && (strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-")) {
+		return fmt.Errorf("label %q must not begin or end with a hyphen", label)
+	}
+
+	return nil
+}
+
+// ValidateIPv4 checks that addr is an IPv4 address in dotted-decimal form, as
+// required for A records. IPv6 addresses, including IPv4-mapped ones, are
+// rejected.
+func ValidateIPv4(addr string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil || ip.To4() == nil || strings.Contains(addr, ":") {
+		return fmt.Errorf(`invalid IPv4 address "%v"`, addr)
+	}
+
+	return nil
+}
+
+// ValidateIPv6 checks that addr is an IPv6 address, as required for AAAA
+// records. Unlike net.IP.To16, which accepts any address, this rejects IPv4
+// addresses written in dotted-decimal form.
+func ValidateIPv6(addr string) error {
+	ip := net.ParseIP(addr)
+	if ip == nil || !strings.Contains(addr, ":") {
+		return fmt.Errorf(`invalid IPv6 address "%v"`, addr)
+	}
+
+	return nil
+}
+
+// ValidateCAA checks the tag and value of a CAA record against RFC 8659. The
+// tag must be 1 to 15 ASCII letters and digits. The values of the issue and
+// issuewild properties must follow the grammar in section 4.2, and that of the
+// iodef property must be a mailto, http, or https URL. The values of other
+// properties are not checked.
+func ValidateCAA(tag string, value string) error {
+	if tag == "" || len(tag) > MaxCAATagLength {
+		return fmt.Errorf(`invalid CAA tag "%v" must be between 1 and %v characters long`, tag, MaxCAATagLength)
+	}
+	for _, c := range tag {
+		if !isAlphaNumeric(c) {
+			return fmt.Errorf(`invalid CAA tag "%v" must contain only ASCII letters and digits`, tag)
+		}
+	}
+
+	switch strings.ToLower(tag) {
+	case "issue", "issuewild":
+		if err := validateCAAIssueValue(value); err != nil {
+			return fmt.Errorf(`invalid CAA %v value "%v": %v`, tag, value, err)
+		}
+	case "iodef":
+		if err := validateCAAIodefValue(value); err != nil {
+			return fmt.Errorf(`invalid CAA iodef value "%v": %v`, value, err)
+		}
+	}
+
+	return nil
+}
+
+// validateCAAIssueValue checks the value of an issue or issuewild property:
+//
+// issue-value = *WSP [issuer-domain-name *WSP] [";" *WSP [parameters *WSP]]
+func validateCAAIssueValue(value string) error {
+	value = strings.Trim(value, " \t")
+
+	domain, params := value, ""
+	if i := strings.Index(value, ";"); i >= 0 {
+		domain, params = strings.TrimRight(value[:i], " \t"), value[i+1:]
+	}
+
+	if domain != "" {
+		for _, label := range strings.Split(domain, ".") {
+			if !isCAALabel(label) {
+				return fmt.Errorf("issuer domain name %q is not valid", domain)
+			}
+		}
+	}
+
+	params = strings.Trim(params, " \t")
+	if params == "" {
+		return nil
+	}
+
+	for _, param := range strings.Split(params, ";") {
+		param = strings.Trim(param, " \t")
+		i := strings.Index(param, "=")
+		if i < 0 {
+			return fmt.Errorf("parameter %q must be of the form TAG=VALUE", param)
+		}
+
+		tag, paramValue := strings.TrimRight(param[:i], " \t"), strings.TrimLeft(param[i+1:], " \t")
+		if !isCAALabel(tag) {
+			return fmt.Errorf("parameter tag %q is not valid", tag)
+		}
+		for _, c := range paramValue {
+			if c < 0x21 || c > 0x7e {
+				return fmt.Errorf("parameter %q contains invalid character %q", tag, c)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCAAIodefValue checks that the value of an iodef property is a URL
+// with a scheme that RFC 8659 allows.
+func validateCAAIodefValue(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("not a URL")
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "mailto":
+		if u.Opaque == "" {
+			return fmt.Errorf("mailto URL has no address")
+		}
+	case "http", "https":
+		if u.Host == "" {
+			return fmt.Errorf("URL has no host")
+		}
+	default:
+		return fmt.Errorf("URL scheme must be mailto, http, or https")
+	}
+
+	return nil
+}
+
+// isCAALabel reports whether s matches the label production of RFC 8659,
+// which is also used for parameter tags:
+//
+// label = (ALPHA / DIGIT) *( *("-") (ALPHA / DIGIT))
+func isCAALabel(s string) bool {
+	if s == "" || s[0] == '-' || s[len(s)-1] == '-' {
+		return false
+	}
+	for _, c := range s {
+		if !isAlphaNumeric(c) && c != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAlphaNumeric(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// ValidateTXTStrings checks that each of the character-strings making up a TXT
+// record is at most 255 bytes long. Longer values must be split into several
+// strings, for example with SplitCharacterString.
+func ValidateTXTStrings(values []string) error {
+	for _, value := range values {
+		if len(value) > MaxCharacterStringLength {
+			return fmt.Errorf("TXT string of %v bytes is longer than the limit of %v; split it into several strings",
+				len(value), MaxCharacterStringLength)
+		}
+	}
+
+	return nil
+}
+
+// ValidateMXExchanges checks the exchanges of the MX records in a record set.
+// Each must be a host name, except that a record set may consist of a single
+// "null MX" record with preference 0 and exchange ".", indicating that the
+// domain accepts no mail (RFC 7505).
+func ValidateMXExchanges(records []dns.MxRecord) error {
+	for _, record := range records {
+		exchange := to.String(record.Exchange)
+		if exchange == "." {
+			if len(records) != 1 || record.Preference == nil || *record.Preference != 0 {
+				return fmt.Errorf(`a null MX record (exchange ".") must have preference 0 and be the only MX record`)
+			}
+			continue
+		}
+		if err := ValidateHostname(exchange); err != nil {
+			return fmt.Errorf("invalid MX exchange: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSRVTargets checks the targets of the SRV records in a record set.
+// Each must be a host name, except that a record set may consist of a single
+// record with target ".", indicating that the service is not available (RFC
+// 2782).
+func ValidateSRVTargets(records []dns.SrvRecord) error {
+	for _, record := range records {
+		target := to.String(record.Target)
+		if target == "." {
+			if len(records) != 1 {
+				return fmt.Errorf(`an SRV record with target "." must be the only SRV record`)
+			}
+			continue
+		}
+		if err := ValidateHostname(target); err != nil {
+			return fmt.Errorf("invalid SRV target: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateCNAMEPlacement checks that a record set of type recordType may exist
+// at the record name name alongside record sets of the types in existing. A
+// CNAME record set cannot be at the zone apex, and cannot share a name with a
+// record set of any other type (RFC 1034, section 3.6.2).
+func ValidateCNAMEPlacement(name string, recordType dns.RecordType, existing []dns.RecordType) error {
+	if recordType == dns.CNAME && name == "@" {
+		return fmt.Errorf("a CNAME record set cannot be created at the zone apex")
+	}
+
+	for _, other := range existing {
+		switch {
+		case other == recordType:
+		case recordType == dns.CNAME:
+			return fmt.Errorf("a CNAME record set cannot be created at %v, which already has %v records", name, other)
+		case other == dns.CNAME:
+			return fmt.Errorf("%v records cannot be created at %v, which has a CNAME record", recordType, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,487 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+type nameValidationTestCase struct {
+	name       string
+	value      string
+	domain     bool
+	hostname   bool
+	recordName bool
+}
+
+var nameValidationTests = []nameValidationTestCase{
+	{
+		name:       "simple",
+		value:      "www.example.com",
+		domain:     true,
+		hostname:   true,
+		recordName: true,
+	},
+	{
+		name:       "absolute",
+		value:      "www.example.com.",
+		domain:     true,
+		hostname:   true,
+		recordName: true,
+	},
+	{
+		name:       "single label",
+		value:      "mail",
+		domain:     true,
+		hostname:   true,
+		recordName: true,
+	},
+	{
+		name:       "digits and hyphens",
+		value:      "123-abc.example.com",
+		domain:     true,
+		hostname:   true,
+		recordName: true,
+	},
+	{
+		name:       "underscores",
+		value:      "_sip._tcp",
+		domain:     true,
+		recordName: true,
+	},
+	{
+		name:       "leading hyphen",
+		value:      "-www.example.com",
+		domain:     true,
+		recordName: true,
+	},
+	{
+		name:       "trailing hyphen",
+		value:      "www-.example.com",
+		domain:     true,
+		recordName: true,
+	},
+	{
+		name:       "wildcard",
+		value:      "*.dev",
+		recordName: true,
+	},
+	{
+		name:  "wildcard not leftmost",
+		value: "dev.*",
+	},
+	{
+		name:       "apex",
+		value:      "@",
+		recordName: true,
+	},
+	{
+		name:  "empty",
+		value: "",
+	},
+	{
+		name:  "root",
+		value: ".",
+	},
+	{
+		name:  "empty label",
+		value: "www..example.com",
+	},
+	{
+		name:  "space",
+		value: "my host.example.com",
+	},
+	{
+		name:  "non-ASCII",
+		value: "bücher.example",
+	},
+	{
+		name:       "longest label",
+		value:      strings.Repeat("a", 63) + ".example.com",
+		domain:     true,
+		hostname:   true,
+		recordName: true,
+	},
+	{
+		name:  "label too long",
+		value: strings.Repeat("a", 64) + ".example.com",
+	},
+	{
+		name:       "longest name",
+		value:      strings.Repeat(strings.Repeat("a", 49)+".", 5) + "abc",
+		domain:     true,
+		hostname:   true,
+		recordName: true,
+	},
+	{
+		name:  "name too long",
+		value: strings.Repeat(strings.Repeat("a", 49)+".", 5) + "abcd",
+	},
+}
+
+func TestNameValidation(t *testing.T) {
+	for _, testCase := range nameValidationTests {
+		t.Run(testCase.name, func(t *testing.T) { testNameValidation(t, testCase) })
+	}
+}
+
+func testNameValidation(t *testing.T, testCase nameValidationTestCase) {
+	check := func(validate func(string) error, valid bool) {
+		if err := validate(testCase.value); valid {
+			assert.NoError(t, err)
+		} else {
+			assert.Error(t, err)
+		}
+	}
+
+	check(ValidateDomainName, testCase.domain)
+	check(ValidateHostname, testCase.hostname)
+	check(ValidateRecordName, testCase.recordName)
+}
+
+func TestNameValidationIDNHint(t *testing.T) {
+	err := ValidateDomainName("bücher.example")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "xn--")
+	}
+}
+
+type ipValidationTestCase struct {
+	name  string
+	value string
+	ipv4  bool
+	ipv6  bool
+}
+
+var ipValidationTests = []ipValidationTestCase{
+	{
+		name:  "IPv4",
+		value: "192.0.2.1",
+		ipv4:  true,
+	},
+	{
+		name:  "IPv6",
+		value: "2001:db8::1",
+		ipv6:  true,
+	},
+	{
+		name:  "IPv6 loopback",
+		value: "::1",
+		ipv6:  true,
+	},
+	{
+		name:  "IPv4-mapped IPv6",
+		value: "::ffff:192.0.2.1",
+		ipv6:  true,
+	},
+	{
+		name:  "IPv4 out of range",
+		value: "192.0.2.256",
+	},
+	{
+		name:  "host name",
+		value: "www.example.com",
+	},
+	{
+		name:  "empty",
+		value: "",
+	},
+}
+
+func TestIPValidation(t *testing.T) {
+	for _, testCase := range ipValidationTests {
+		t.Run(testCase.name, func(t *testing.T) { testIPValidation(t, testCase) })
+	}
+}
+
+func testIPValidation(t *testing.T, testCase ipValidationTestCase) {
+	if err := ValidateIPv4(testCase.value); testCase.ipv4 {
+		assert.NoError(t, err)
+	} else {
+		assert.EqualError(t, err, `invalid IPv4 address "`+testCase.value+`"`)
+	}
+
+	if err := ValidateIPv6(testCase.value); testCase.ipv6 {
+		assert.NoError(t, err)
+	} else {
+		assert.EqualError(t, err, `invalid IPv6 address "`+testCase.value+`"`)
+	}
+}
+
+type caaValidationTestCase struct {
+	name  string
+	tag   string
+	value string
+	valid bool
+}
+
+var caaValidationTests = []caaValidationTestCase{
+	{
+		name:  "issue",
+		tag:   "issue",
+		value: "letsencrypt.org",
+		valid: true,
+	},
+	{
+		name:  "issue with parameters",
+		tag:   "issue",
+		value: "ca.example.net; account=230123; policy=ev",
+		valid: true,
+	},
+	{
+		name:  "issue with whitespace",
+		tag:   "issue",
+		value: " ca.example.net ;\taccount = 230123 ",
+		valid: true,
+	},
+	{
+		name:  "issue nobody",
+		tag:   "issue",
+		value: ";",
+		valid: true,
+	},
+	{
+		name:  "issue empty",
+		tag:   "issue",
+		value: "",
+		valid: true,
+	},
+	{
+		name:  "issuewild upper case",
+		tag:   "ISSUEWILD",
+		value: "ca.example.net",
+		valid: true,
+	},
+	{
+		name:  "issue invalid domain",
+		tag:   "issue",
+		value: "ca_example.net",
+	},
+	{
+		name:  "issue parameter without value",
+		tag:   "issue",
+		value: "ca.example.net; account",
+	},
+	{
+		name:  "issue parameter with space in value",
+		tag:   "issue",
+		value: "ca.example.net; account=1 2",
+	},
+	{
+		name:  "issue invalid parameter tag",
+		tag:   "issue",
+		value: "ca.example.net; -account=1",
+	},
+	{
+		name:  "iodef mailto",
+		tag:   "iodef",
+		value: "mailto:security@example.com",
+		valid: true,
+	},
+	{
+		name:  "iodef https",
+		tag:   "iodef",
+		value: "https://iodef.example.com/",
+		valid: true,
+	},
+	{
+		name:  "iodef bare address",
+		tag:   "iodef",
+		value: "security@example.com",
+	},
+	{
+		name:  "iodef other scheme",
+		tag:   "iodef",
+		value: "ftp://iodef.example.com/",
+	},
+	{
+		name:  "iodef empty mailto",
+		tag:   "iodef",
+		value: "mailto:",
+	},
+	{
+		name:  "unknown tag",
+		tag:   "tbs",
+		value: "anything at all",
+		valid: true,
+	},
+	{
+		name:  "longest tag",
+		tag:   "abcdefghijklmno",
+		value: "",
+		valid: true,
+	},
+	{
+		name:  "tag too long",
+		tag:   "abcdefghijklmnop",
+		value: "",
+	},
+	{
+		name:  "empty tag",
+		tag:   "",
+		value: "letsencrypt.org",
+	},
+	{
+		name:  "tag with hyphen",
+		tag:   "issue-wild",
+		value: "letsencrypt.org",
+	},
+}
+
+func TestCAAValidation(t *testing.T) {
+	for _, testCase := range caaValidationTests {
+		t.Run(testCase.name, func(t *testing.T) { testCAAValidation(t, testCase) })
+	}
+}
+
+func testCAAValidation(t *testing.T, testCase caaValidationTestCase) {
+	if err := ValidateCAA(testCase.tag, testCase.value); testCase.valid {
+		assert.NoError(t, err)
+	} else {
+		assert.Error(t, err)
+	}
+}
+
+func TestValidateTXTStrings(t *testing.T) {
+	assert.NoError(t, ValidateTXTStrings([]string{"", "v=spf1 -all", strings.Repeat("a", 255)}))
+	assert.EqualError(t, ValidateTXTStrings([]string{"short", strings.Repeat("a", 256)}),
+		"TXT string of 256 bytes is longer than the limit of 255; split it into several strings")
+}
+
+func mxRecord(preference int32, exchange string) dns.MxRecord {
+	return dns.MxRecord{Preference: to.Int32Ptr(preference), Exchange: to.StringPtr(exchange)}
+}
+
+func srvRecord(target string) dns.SrvRecord {
+	return dns.SrvRecord{
+		Priority: to.Int32Ptr(10),
+		Weight:   to.Int32Ptr(60),
+		Port:     to.Int32Ptr(5060),
+		Target:   to.StringPtr(target),
+	}
+}
+
+type targetValidationTestCase struct {
+	name    string
+	mx      []dns.MxRecord
+	srv     []dns.SrvRecord
+	invalid bool
+}
+
+var targetValidationTests = []targetValidationTestCase{
+	{
+		name: "host names",
+		mx:   []dns.MxRecord{mxRecord(10, "mail1.example.com"), mxRecord(20, "mail2.example.com.")},
+		srv:  []dns.SrvRecord{srvRecord("sip1.example.com"), srvRecord("sip2.example.com.")},
+	},
+	{
+		name: "null",
+		mx:   []dns.MxRecord{mxRecord(0, ".")},
+		srv:  []dns.SrvRecord{srvRecord(".")},
+	},
+	{
+		name:    "null with others",
+		mx:      []dns.MxRecord{mxRecord(0, "."), mxRecord(10, "mail.example.com")},
+		srv:     []dns.SrvRecord{srvRecord("."), srvRecord("sip.example.com")},
+		invalid: true,
+	},
+	{
+		name:    "underscore",
+		mx:      []dns.MxRecord{mxRecord(10, "mail_1.example.com")},
+		srv:     []dns.SrvRecord{srvRecord("_sip.example.com")},
+		invalid: true,
+	},
+	{
+		name:    "empty",
+		mx:      []dns.MxRecord{mxRecord(10, "")},
+		srv:     []dns.SrvRecord{srvRecord("")},
+		invalid: true,
+	},
+}
+
+func TestTargetValidation(t *testing.T) {
+	for _, testCase := range targetValidationTests {
+		t.Run(testCase.name, func(t *testing.T) { testTargetValidation(t, testCase) })
+	}
+}
+
+func testTargetValidation(t *testing.T, testCase targetValidationTestCase) {
+	if testCase.invalid {
+		assert.Error(t, ValidateMXExchanges(testCase.mx))
+		assert.Error(t, ValidateSRVTargets(testCase.srv))
+	} else {
+		assert.NoError(t, ValidateMXExchanges(testCase.mx))
+		assert.NoError(t, ValidateSRVTargets(testCase.srv))
+	}
+}
+
+func TestValidateMXExchangesNullPreference(t *testing.T) {
+	assert.EqualError(t, ValidateMXExchanges([]dns.MxRecord{mxRecord(10, ".")}),
+		`a null MX record (exchange ".") must have preference 0 and be the only MX record`)
+}
+
+type cnamePlacementTestCase struct {
+	name       string
+	recordName string
+	recordType dns.RecordType
+	existing   []dns.RecordType
+	err        string
+}
+
+var cnamePlacementTests = []cnamePlacementTestCase{
+	{
+		name:       "new CNAME",
+		recordName: "www",
+		recordType: dns.CNAME,
+	},
+	{
+		name:       "replace CNAME",
+		recordName: "www",
+		recordType: dns.CNAME,
+		existing:   []dns.RecordType{dns.CNAME},
+	},
+	{
+		name:       "other types",
+		recordName: "www",
+		recordType: dns.A,
+		existing:   []dns.RecordType{dns.AAAA, dns.TXT},
+	},
+	{
+		name:       "CNAME at apex",
+		recordName: "@",
+		recordType: dns.CNAME,
+		err:        "a CNAME record set cannot be created at the zone apex",
+	},
+	{
+		name:       "CNAME beside other types",
+		recordName: "www",
+		recordType: dns.CNAME,
+		existing:   []dns.RecordType{dns.A},
+		err:        "a CNAME record set cannot be created at www, which already has A records",
+	},
+	{
+		name:       "other type beside CNAME",
+		recordName: "www",
+		recordType: dns.TXT,
+		existing:   []dns.RecordType{dns.CNAME},
+		err:        "TXT records cannot be created at www, which has a CNAME record",
+	},
+}
+
+func TestValidateCNAMEPlacement(t *testing.T) {
+	for _, testCase := range cnamePlacementTests {
+		t.Run(testCase.name, func(t *testing.T) { testValidateCNAMEPlacement(t, testCase) })
+	}
+}
+
+func testValidateCNAMEPlacement(t *testing.T, testCase cnamePlacementTestCase) {
+	err := ValidateCNAMEPlacement(testCase.recordName, testCase.recordType, testCase.existing)
+	if testCase.err == "" {
+		assert.NoError(t, err)
+	} else {
+		assert.EqualError(t, err, testCase.err)
+	}
+}
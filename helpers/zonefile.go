@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+)
+
+// DefaultZoneFileTTL is the $TTL written to exported zone files that have no
+// SOA record from which to take a TTL.
+const DefaultZoneFileTTL = 3600
+
+// WriteZoneFile writes the record sets of a zone to w as an RFC 1035 master
+// file. The output is canonical: record sets are sorted by owner name in DNSSEC
+// canonical order (RFC 4034, section 6.1), with SOA records first and NS
+// records second at each name, and the records within each set are sorted, so
+// the same set of records always produces the same file.
+func WriteZoneFile(w io.Writer, zone string, rrsets []dns.RecordSet) error {
+	zone = strings.TrimRight(zone, ".")
+
+	type entry struct {
+		name       string
+		recordType dns.RecordType
+		ttl        int64
+		values     []string
+	}
+
+	defaultTTL := int64(DefaultZoneFileTTL)
+	entries := []entry{}
+	for _, rrset := range rrsets {
+		e := entry{
+			name:       zoneFileRecordName(rrset, zone),
+			recordType: RecordSetType(rrset),
+		}
+		if rrset.RecordSetProperties != nil {
+			e.ttl = to.Int64(rrset.TTL)
+		}
+		e.values = ZoneFileValues(e.recordType, rrset.RecordSetProperties)
+		sort.Strings(e.values)
+
+		if e.recordType == dns.SOA && e.name == "@" {
+			defaultTTL = e.ttl
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if c := CompareRecordNames(entries[i].name, entries[j].name); c != 0 {
+			return c < 0
+		}
+		return recordTypeOrder(entries[i].recordType) < recordTypeOrder(entries[j].recordType)
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "$ORIGIN %v.\n", zone)
+	fmt.Fprintf(bw, "$TTL %v\n", defaultTTL)
+
+	for _, e := range entries {
+		for _, value := range e.values {
+			fmt.Fprintf(bw, "%v\t%v\tIN\t%v\t%v\n", e.name, e.ttl, e.recordType, value)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// zoneFileRecordName determines the owner name of a record set relative to the
+// zone, preferring the FQDN reported by Azure.
+func zoneFileRecordName(rrset dns.RecordSet, zone string) string {
+	if rrset.RecordSetProperties != nil && rrset.Fqdn != nil {
+		return GenerateRecordName(*rrset.Fqdn, zone, false)
+	}
+
+	return GenerateRecordName(to.String(rrset.Name), zone, true)
+}
+
+// CompareRecordNames compares two zone-relative record names in DNSSEC
+// canonical order, comparing labels case-insensitively from right to left. The
+// apex, @, sorts before every other name. The result is negative if a sorts
+// before b, positive if it sorts after, and zero if they are equal.
+func CompareRecordNames(a string, b string) int {
+	aLabels := recordNameLabels(a)
+	bLabels := recordNameLabels(b)
+
+	for i := 1; i <= len(aLabels) && i <= len(bLabels); i++ {
+		aLabel := strings.ToLower(aLabels[len(aLabels)-i])
+		bLabel := strings.ToLower(bLabels[len(bLabels)-i])
+		if c := strings.Compare(aLabel, bLabel); c != 0 {
+			return c
+		}
+	}
+
+	return len(aLabels) - len(bLabels)
+}
+
+func recordNameLabels(name string) []string {
+	name = strings.TrimRight(name, ".")
+	if name == "" || name == "@" {
+		return nil
+	}
+
+	return strings.Split(name, ".")
+}
+
+// recordTypeOrder determines the order in which record sets with the same name
+// are written to a zone file: SOA, then NS, then every other type
+// alphabetically.
+func recordTypeOrder(recordType dns.RecordType) string {
+	switch recordType {
+	case dns.SOA:
+		return "0"
+	case dns.NS:
+		return "1"
+	}
+
+	return "2" + string(recordType)
+}
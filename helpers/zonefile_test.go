@@ -0,0 +1,140 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/stretchr/testify/assert"
+)
+
+type compareRecordNamesTestCase struct {
+	a        string
+	b        string
+	expected int
+}
+
+var compareRecordNamesTests = []compareRecordNamesTestCase{
+	{"@", "@", 0},
+	{"@", "www", -1},
+	{"www", "@", 1},
+	{"a", "b", -1},
+	{"WWW", "www", 0},
+	{"www", "a.www", -1},
+	{"b.a", "a.b", -1},
+	{"*", "a", -1},
+	{"z", "a.b", 1},
+}
+
+func TestCompareRecordNames(t *testing.T) {
+	for _, testCase := range compareRecordNamesTests {
+		name := fmt.Sprintf("%v<=>%v", testCase.a, testCase.b)
+		t.Run(name, func(t *testing.T) { testCompareRecordNames(t, testCase) })
+	}
+}
+
+func testCompareRecordNames(t *testing.T, testCase compareRecordNamesTestCase) {
+	result := CompareRecordNames(testCase.a, testCase.b)
+	switch {
+	case testCase.expected < 0:
+		assert.True(t, result < 0, "expected %v to sort before %v", testCase.a, testCase.b)
+	case testCase.expected > 0:
+		assert.True(t, result > 0, "expected %v to sort after %v", testCase.a, testCase.b)
+	default:
+		assert.Equal(t, 0, result)
+	}
+}
+
+func newTestRecordSet(name string, recordType dns.RecordType, ttl int64, props dns.RecordSetProperties) dns.RecordSet {
+	props.TTL = to.Int64Ptr(ttl)
+	if name == "@" {
+		props.Fqdn = to.StringPtr("example.com.")
+	} else {
+		props.Fqdn = to.StringPtr(name + ".example.com.")
+	}
+
+	return dns.RecordSet{
+		Name:                to.StringPtr(name),
+		Type:                to.StringPtr("Microsoft.Network/dnszones/" + string(recordType)),
+		RecordSetProperties: &props,
+	}
+}
+
+func TestWriteZoneFile(t *testing.T) {
+	longValue := strings.Repeat("a", 300)
+
+	rrsets := []dns.RecordSet{
+		newTestRecordSet("www", dns.CNAME, 300, dns.RecordSetProperties{
+			CnameRecord: &dns.CnameRecord{Cname: to.StringPtr("example.com")},
+		}),
+		newTestRecordSet("@", dns.MX, 3600, dns.RecordSetProperties{
+			MxRecords: &[]dns.MxRecord{
+				{Preference: to.Int32Ptr(20), Exchange: to.StringPtr("mail2.example.com")},
+				{Preference: to.Int32Ptr(10), Exchange: to.StringPtr("mail1.example.com.")},
+			},
+		}),
+		newTestRecordSet("@", dns.SOA, 7200, dns.RecordSetProperties{
+			SoaRecord: &dns.SoaRecord{
+				Host:         to.StringPtr("ns1-01.azure-dns.com."),
+				Email:        to.StringPtr("azuredns-hostmaster.microsoft.com"),
+				SerialNumber: to.Int64Ptr(1),
+				RefreshTime:  to.Int64Ptr(3600),
+				RetryTime:    to.Int64Ptr(300),
+				ExpireTime:   to.Int64Ptr(2419200),
+				MinimumTTL:   to.Int64Ptr(300),
+			},
+		}),
+		newTestRecordSet("a.sub", dns.TXT, 60, dns.RecordSetProperties{
+			TxtRecords: &[]dns.TxtRecord{{Value: &[]string{longValue}}},
+		}),
+		newTestRecordSet("@", dns.CAA, 3600, dns.RecordSetProperties{
+			CaaRecords: &[]dns.CaaRecord{{Flags: to.Int32Ptr(0), Tag: to.StringPtr("iodef"), Value: to.StringPtr(`mailto:"dns"@example.com`)}},
+		}),
+		newTestRecordSet("@", dns.NS, 172800, dns.RecordSetProperties{
+			NsRecords: &[]dns.NsRecord{{Nsdname: to.StringPtr("ns1-01.azure-dns.com.")}},
+		}),
+	}
+
+	expected := `$ORIGIN example.com.
+$TTL 7200
+@	7200	IN	SOA	ns1-01.azure-dns.com. azuredns-hostmaster.microsoft.com. 1 3600 300 2419200 300
+@	172800	IN	NS	ns1-01.azure-dns.com.
+@	3600	IN	CAA	0 iodef "mailto:\"dns\"@example.com"
+@	3600	IN	MX	10 mail1.example.com.
+@	3600	IN	MX	20 mail2.example.com.
+a.sub	60	IN	TXT	"` + longValue[:255] + `" "` + longValue[255:] + `"
+www	300	IN	CNAME	example.com.
+`
+
+	var out bytes.Buffer
+	err := WriteZoneFile(&out, "example.com.", rrsets)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, out.String())
+
+	// The output must not depend on the order in which Azure lists record sets.
+	reversed := []dns.RecordSet{}
+	for i := len(rrsets) - 1; i >= 0; i-- {
+		reversed = append(reversed, rrsets[i])
+	}
+
+	var reversedOut bytes.Buffer
+	err = WriteZoneFile(&reversedOut, "example.com", reversed)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, reversedOut.String())
+}
+
+func TestWriteZoneFileDefaultTTL(t *testing.T) {
+	rrsets := []dns.RecordSet{
+		newTestRecordSet("www", dns.A, 300, dns.RecordSetProperties{
+			ARecords: &[]dns.ARecord{{Ipv4Address: to.StringPtr("1.1.1.1")}},
+		}),
+	}
+
+	var out bytes.Buffer
+	err := WriteZoneFile(&out, "example.com", rrsets)
+	assert.NoError(t, err)
+	assert.Equal(t, "$ORIGIN example.com.\n$TTL 3600\nwww\t300\tIN\tA\t1.1.1.1\n", out.String())
+}
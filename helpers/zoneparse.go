@@ -0,0 +1,486 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+)
+
+// maxIncludeDepth limits how deeply $INCLUDE directives may be nested, which
+// guards against files that include themselves.
+const maxIncludeDepth = 10
+
+// ZoneFileRecord is a single resource record read from a zone file.
+type ZoneFileRecord struct {
+	// Name is the owner name of the record, relative to the zone, or @ for the
+	// apex.
+	Name string
+	// TTL is the record's TTL in seconds.
+	TTL int64
+	// Type is the record type, in upper case.
+	Type dns.RecordType
+	// Fields holds the RDATA of the record, split into fields with quoting and
+	// escapes removed. Domain names in records of known types are
+	// fully-qualified, without a trailing dot.
+	Fields []string
+	// Source identifies where the record was read, as FILE:LINE.
+	Source string
+}
+
+// zoneFileToken is a single token of a zone file entry.
+type zoneFileToken struct {
+	text   string
+	quoted bool
+}
+
+// zoneFileEntry is a logical line of a zone file, which may span several
+// physical lines if it uses parentheses.
+type zoneFileEntry struct {
+	tokens     []zoneFileToken
+	blankOwner bool
+	line       int
+}
+
+// zoneFileParser holds the state that persists between entries in a zone file.
+type zoneFileParser struct {
+	zone          string
+	origin        string
+	defaultTTL    int64
+	hasDefaultTTL bool
+	lastTTL       int64
+	hasLastTTL    bool
+	lastOwner     string
+	records       []ZoneFileRecord
+}
+
+// ParseZoneFile reads an RFC 1035 master file describing the given zone. It
+// supports the $ORIGIN, $TTL, and $INCLUDE directives, parenthesized entries
+// spanning multiple lines, comments, quoted strings, escapes, and owner names
+// that are relative, absolute, @, or omitted to repeat the previous owner.
+// Only the IN class is accepted. Included files are resolved relative to the
+// directory containing filename. Records whose owner names fall outside of the
+// zone are rejected.
+func ParseZoneFile(r io.Reader, filename string, zone string) ([]ZoneFileRecord, error) {
+	zone = Fqdn(strings.TrimRight(zone, "."))
+	p := &zoneFileParser{zone: zone, origin: zone}
+
+	if err := p.parse(r, filename, 0); err != nil {
+		return nil, err
+	}
+
+	return p.records, nil
+}
+
+func (p *zoneFileParser) parse(r io.Reader, filename string, depth int) error {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	entries, err := tokenizeZoneFile(contents)
+	if err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+
+	for _, entry := range entries {
+		if err := p.parseEntry(entry, filename, depth); err != nil {
+			return fmt.Errorf("%v:%v: %v", filename, entry.line, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *zoneFileParser) parseEntry(entry zoneFileEntry, filename string, depth int) error {
+	tokens := entry.tokens
+
+	if !entry.blankOwner && !tokens[0].quoted && strings.HasPrefix(tokens[0].text, "$") {
+		return p.parseDirective(tokens, filename, depth)
+	}
+
+	var owner string
+	if entry.blankOwner {
+		if p.lastOwner == "" {
+			return fmt.Errorf("record has no owner name")
+		}
+		owner = p.lastOwner
+	} else {
+		owner = p.qualify(tokens[0].text)
+		tokens = tokens[1:]
+	}
+	p.lastOwner = owner
+
+	// RFC 1035 specifies that a record without a TTL uses the TTL of the
+	// previous record; RFC 2308 adds the $TTL directive, which takes
+	// precedence.
+	ttl, hasTTL := p.lastTTL, p.hasLastTTL
+	if p.hasDefaultTTL {
+		ttl, hasTTL = p.defaultTTL, true
+	}
+
+	for i := 0; i < 2 && len(tokens) > 0 && !tokens[0].quoted; i++ {
+		if value, ok := ParseTTL(tokens[0].text); ok {
+			ttl, hasTTL = value, true
+			tokens = tokens[1:]
+			continue
+		}
+
+		class := strings.ToUpper(tokens[0].text)
+		if class == "IN" {
+			tokens = tokens[1:]
+			continue
+		}
+		if class == "CH" || class == "CS" || class == "HS" || class == "ANY" {
+			return fmt.Errorf("unsupported class %v", tokens[0].text)
+		}
+
+		break
+	}
+
+	if len(tokens) == 0 {
+		return fmt.Errorf("record has no type")
+	}
+	if !hasTTL {
+		return fmt.Errorf("record has no TTL and no $TTL directive precedes it")
+	}
+
+	name, err := p.relativeName(owner)
+	if err != nil {
+		return err
+	}
+
+	record := ZoneFileRecord{
+		Name:   name,
+		TTL:    ttl,
+		Type:   dns.RecordType(strings.ToUpper(tokens[0].text)),
+		Fields: []string{},
+		Source: fmt.Sprintf("%v:%v", filename, entry.line),
+	}
+	for _, token := range tokens[1:] {
+		record.Fields = append(record.Fields, token.text)
+	}
+
+	for _, i := range domainNameFields(record.Type) {
+		if i < len(record.Fields) {
+			record.Fields[i] = strings.TrimRight(p.qualify(record.Fields[i]), ".")
+		}
+	}
+
+	p.lastTTL, p.hasLastTTL = ttl, true
+	p.records = append(p.records, record)
+	return nil
+}
+
+func (p *zoneFileParser) parseDirective(tokens []zoneFileToken, filename string, depth int) error {
+	switch strings.ToUpper(tokens[0].text) {
+	case "$ORIGIN":
+		if len(tokens) != 2 {
+			return fmt.Errorf("$ORIGIN requires exactly one domain name")
+		}
+		p.origin = p.qualify(tokens[1].text)
+	case "$TTL":
+		if len(tokens) != 2 {
+			return fmt.Errorf("$TTL requires exactly one TTL")
+		}
+		ttl, ok := ParseTTL(tokens[1].text)
+		if !ok {
+			return fmt.Errorf("invalid TTL %q", tokens[1].text)
+		}
+		p.defaultTTL, p.hasDefaultTTL = ttl, true
+	case "$INCLUDE":
+		if len(tokens) < 2 || len(tokens) > 3 {
+			return fmt.Errorf("$INCLUDE requires a file name and an optional domain name")
+		}
+		if depth >= maxIncludeDepth {
+			return fmt.Errorf("$INCLUDE nested too deeply")
+		}
+
+		path := tokens[1].text
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(filename), path)
+		}
+
+		// The origin and owner name are restored once the included file has
+		// been read.
+		origin, lastOwner := p.origin, p.lastOwner
+		if len(tokens) == 3 {
+			p.origin = p.qualify(tokens[2].text)
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := p.parse(file, path, depth+1); err != nil {
+			return err
+		}
+
+		p.origin, p.lastOwner = origin, lastOwner
+	default:
+		return fmt.Errorf("unsupported directive %v", tokens[0].text)
+	}
+
+	return nil
+}
+
+// qualify makes a name from the zone file absolute, with a trailing dot, by
+// appending the current origin if necessary.
+func (p *zoneFileParser) qualify(name string) string {
+	switch {
+	case name == "@":
+		return p.origin
+	case strings.HasSuffix(name, "."):
+		return name
+	}
+
+	return name + "." + p.origin
+}
+
+// relativeName converts an absolute owner name into a name relative to the
+// zone, or @ for the apex.
+func (p *zoneFileParser) relativeName(owner string) (string, error) {
+	lowerOwner := strings.ToLower(owner)
+	lowerZone := strings.ToLower(p.zone)
+
+	if lowerOwner == lowerZone {
+		return "@", nil
+	}
+	if !strings.HasSuffix(lowerOwner, "."+lowerZone) {
+		return "", fmt.Errorf("owner name %v is outside of zone %v", owner, p.zone)
+	}
+
+	return owner[:len(owner)-len(lowerZone)-1], nil
+}
+
+// ParseRecordFields splits the RDATA of a single record in zone file
+// presentation format, such as 10 mail.example.com or 0 issue "example.org",
+// into fields, removing quoting and escapes. Because no origin is available,
+// domain names are taken to be fully-qualified, and any trailing dot on them is
+// removed.
+func ParseRecordFields(recordType dns.RecordType, value string) ([]string, error) {
+	entries, err := tokenizeZoneFile([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 1 {
+		return nil, fmt.Errorf("record %q spans multiple lines", value)
+	}
+
+	fields := []string{}
+	for _, entry := range entries {
+		for _, token := range entry.tokens {
+			fields = append(fields, token.text)
+		}
+	}
+
+	for _, i := range domainNameFields(recordType) {
+		if i < len(fields) {
+			fields[i] = strings.TrimRight(fields[i], ".")
+		}
+	}
+
+	return fields, nil
+}
+
+// domainNameFields returns the indices of the RDATA fields of a record type that
+// hold domain names.
+func domainNameFields(recordType dns.RecordType) []int {
+	switch recordType {
+	case dns.CNAME, dns.NS, dns.PTR:
+		return []int{0}
+	case dns.MX:
+		return []int{1}
+	case dns.SRV:
+		return []int{3}
+	case dns.SOA:
+		return []int{0, 1}
+	}
+
+	return nil
+}
+
+// ParseTTL parses a TTL from a zone file. In addition to a plain number of
+// seconds, it accepts the BIND syntax of numbers followed by the units w, d, h,
+// m, or s, such as 1h30m. The second result reports whether value was a valid
+// TTL.
+func ParseTTL(value string) (int64, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if ttl, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ttl, ttl >= 0
+	}
+
+	var total, current int64
+	var hasDigits bool
+	for _, c := range strings.ToLower(value) {
+		if c >= '0' && c <= '9' {
+			current = current*10 + int64(c-'0')
+			hasDigits = true
+			continue
+		}
+
+		if !hasDigits {
+			return 0, false
+		}
+
+		switch c {
+		case 'w':
+			current *= 7 * 24 * 60 * 60
+		case 'd':
+			current *= 24 * 60 * 60
+		case 'h':
+			current *= 60 * 60
+		case 'm':
+			current *= 60
+		case 's':
+		default:
+			return 0, false
+		}
+
+		total += current
+		current, hasDigits = 0, false
+	}
+
+	if hasDigits {
+		return 0, false
+	}
+
+	return total, true
+}
+
+// tokenizeZoneFile splits the contents of a zone file into entries, removing
+// comments and joining parenthesized entries that span multiple lines.
+func tokenizeZoneFile(contents []byte) ([]zoneFileEntry, error) {
+	entries := []zoneFileEntry{}
+	entry := zoneFileEntry{line: 1}
+	line := 1
+	depth := 0
+	startOfLine := true
+
+	for i := 0; i < len(contents); {
+		c := contents[i]
+
+		switch {
+		case c == '\n':
+			line++
+			i++
+			startOfLine = true
+			if depth == 0 {
+				if len(entry.tokens) > 0 {
+					entries = append(entries, entry)
+				}
+				entry = zoneFileEntry{line: line}
+			}
+			continue
+		case c == ';':
+			for i < len(contents) && contents[i] != '\n' {
+				i++
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			if startOfLine && depth == 0 && len(entry.tokens) == 0 {
+				entry.blankOwner = true
+			}
+			i++
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("line %v: unbalanced parenthesis", line)
+			}
+			depth--
+			i++
+		case c == '"':
+			text, n, err := readZoneFileString(contents[i+1:], true)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+			entry.tokens = append(entry.tokens, zoneFileToken{text: text, quoted: true})
+			i += n + 1
+		default:
+			text, n, err := readZoneFileString(contents[i:], false)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: %v", line, err)
+			}
+			entry.tokens = append(entry.tokens, zoneFileToken{text: text})
+			i += n
+		}
+
+		startOfLine = false
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("line %v: unbalanced parenthesis", entry.line)
+	}
+	if len(entry.tokens) > 0 {
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// readZoneFileString reads a single token from the start of contents,
+// interpreting escapes. If quoted is true, the token ends at the closing
+// double quote, which is consumed; otherwise, it ends at whitespace or a
+// special character. The number of bytes consumed is returned along with the
+// token.
+func readZoneFileString(contents []byte, quoted bool) (string, int, error) {
+	var token strings.Builder
+
+	for i := 0; i < len(contents); i++ {
+		c := contents[i]
+
+		if quoted {
+			switch c {
+			case '"':
+				return token.String(), i + 1, nil
+			case '\n':
+				return "", 0, fmt.Errorf("unterminated quoted string")
+			}
+		} else if strings.IndexByte(" \t\r\n;()\"", c) >= 0 {
+			return token.String(), i, nil
+		}
+
+		if c != '\\' {
+			token.WriteByte(c)
+			continue
+		}
+
+		if i+1 >= len(contents) {
+			return "", 0, fmt.Errorf("incomplete escape sequence")
+		}
+
+		if i+3 < len(contents) && isDigit(contents[i+1]) && isDigit(contents[i+2]) && isDigit(contents[i+3]) {
+			value, _ := strconv.Atoi(string(contents[i+1 : i+4]))
+			if value > 255 {
+				return "", 0, fmt.Errorf("invalid escape sequence \\%s", contents[i+1:i+4])
+			}
+			token.WriteByte(byte(value))
+			i += 3
+			continue
+		}
+
+		token.WriteByte(contents[i+1])
+		i++
+	}
+
+	if quoted {
+		return "", 0, fmt.Errorf("unterminated quoted string")
+	}
+
+	return token.String(), len(contents), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
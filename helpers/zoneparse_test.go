@@ -0,0 +1,180 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+type parseTTLTestCase struct {
+	value       string
+	expectedTTL int64
+	expectedOK  bool
+}
+
+var parseTTLTests = []parseTTLTestCase{
+	{"300", 300, true},
+	{"0", 0, true},
+	{"1h", 3600, true},
+	{"1H30m", 5400, true},
+	{"1w2d", 777600, true},
+	{"10s", 10, true},
+	{"", 0, false},
+	{"-1", 0, false},
+	{"h", 0, false},
+	{"1h30", 0, false},
+	{"1x", 0, false},
+	{"IN", 0, false},
+}
+
+func TestParseTTL(t *testing.T) {
+	for _, testCase := range parseTTLTests {
+		t.Run(testCase.value, func(t *testing.T) { testParseTTL(t, testCase) })
+	}
+}
+
+func testParseTTL(t *testing.T, testCase parseTTLTestCase) {
+	ttl, ok := ParseTTL(testCase.value)
+	assert.Equal(t, testCase.expectedOK, ok)
+	if testCase.expectedOK {
+		assert.Equal(t, testCase.expectedTTL, ttl)
+	}
+}
+
+func TestParseZoneFile(t *testing.T) {
+	contents := `$ORIGIN example.com.
+$TTL 1h
+; The SOA record spans several lines.
+@	IN	SOA	ns1 hostmaster (
+		2018010101 ; serial
+		3600       ; refresh
+		300        ; retry
+		2419200    ; expire
+		300 )      ; minimum
+	IN	NS	ns1.example.net.
+	300	IN	MX	10 mail
+	IN 300	MX	20 mail.example.net.
+www	A	192.0.2.1
+WWW.example.com.	A	192.0.2.2
+txt	TXT	"v=spf1 -all" "with \"quotes\"" unquoted\;semicolon
+caa	CAA	0 issue "letsencrypt.org"
+_sip._tcp	SRV	10 60 5060 sip
+$ORIGIN sub.example.com.
+host	PTR	target
+$INCLUDE testdata/include.zone
+after	A	192.0.2.20
+`
+
+	records, err := ParseZoneFile(strings.NewReader(contents), "example.zone", "example.com")
+	assert.NoError(t, err)
+
+	expected := []ZoneFileRecord{
+		{"@", 3600, dns.SOA, []string{"ns1.example.com", "hostmaster.example.com", "2018010101", "3600", "300", "2419200", "300"}, "example.zone:4"},
+		{"@", 3600, dns.NS, []string{"ns1.example.net"}, "example.zone:10"},
+		{"@", 300, dns.MX, []string{"10", "mail.example.com"}, "example.zone:11"},
+		{"@", 300, dns.MX, []string{"20", "mail.example.net"}, "example.zone:12"},
+		{"www", 3600, dns.A, []string{"192.0.2.1"}, "example.zone:13"},
+		{"WWW", 3600, dns.A, []string{"192.0.2.2"}, "example.zone:14"},
+		{"txt", 3600, dns.TXT, []string{"v=spf1 -all", `with "quotes"`, "unquoted;semicolon"}, "example.zone:15"},
+		{"caa", 3600, dns.CAA, []string{"0", "issue", "letsencrypt.org"}, "example.zone:16"},
+		{"_sip._tcp", 3600, dns.SRV, []string{"10", "60", "5060", "sip.example.com"}, "example.zone:17"},
+		{"host.sub", 3600, dns.PTR, []string{"target.sub.example.com"}, "example.zone:19"},
+		{"sub", 3600, dns.A, []string{"192.0.2.10"}, "testdata/include.zone:2"},
+		{"www.sub", 3600, dns.CNAME, []string{"sub.example.com"}, "testdata/include.zone:3"},
+		{"after.sub", 3600, dns.A, []string{"192.0.2.20"}, "example.zone:21"},
+	}
+
+	assert.Equal(t, expected, records)
+}
+
+func TestParseZoneFileIncludeOrigin(t *testing.T) {
+	contents := "$TTL 60\n$INCLUDE testdata/include.zone other.example.com.\nafter A 192.0.2.1\n"
+
+	records, err := ParseZoneFile(strings.NewReader(contents), "example.zone", "example.com")
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "other", records[0].Name)
+		assert.Equal(t, "www.other", records[1].Name)
+		assert.Equal(t, []string{"other.example.com"}, records[1].Fields)
+		assert.Equal(t, "after", records[2].Name)
+	}
+}
+
+func TestParseZoneFilePreviousTTL(t *testing.T) {
+	contents := "a 120 A 192.0.2.1\nb A 192.0.2.2\n"
+
+	records, err := ParseZoneFile(strings.NewReader(contents), "example.zone", "example.com")
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, int64(120), records[1].TTL)
+	}
+}
+
+type parseZoneFileErrorTestCase struct {
+	name          string
+	contents      string
+	expectedError string
+}
+
+var parseZoneFileErrorTests = []parseZoneFileErrorTestCase{
+	{"no TTL", "www A 192.0.2.1\n", "example.zone:1: record has no TTL and no $TTL directive precedes it"},
+	{"outside zone", "$TTL 60\nwww.example.net. A 192.0.2.1\n", "example.zone:2: owner name www.example.net. is outside of zone example.com."},
+	{"no owner", "$TTL 60\n A 192.0.2.1\n", "example.zone:2: record has no owner name"},
+	{"class", "$TTL 60\nwww CH A 192.0.2.1\n", "example.zone:2: unsupported class CH"},
+	{"no type", "$TTL 60\nwww 60 IN\n", "example.zone:2: record has no type"},
+	{"directive", "$GENERATE 1-10 host$ A 192.0.2.$\n", "example.zone:1: unsupported directive $GENERATE"},
+	{"unbalanced", "$TTL 60\n@ SOA ns1 hostmaster ( 1 2 3 4 5\n", "example.zone: line 2: unbalanced parenthesis"},
+	{"unterminated", "$TTL 60\ntxt TXT \"open\n", "example.zone: line 2: unterminated quoted string"},
+	{"missing include", "$INCLUDE testdata/missing.zone\n", "example.zone:1: open testdata/missing.zone: no such file or directory"},
+}
+
+func TestParseZoneFileErrors(t *testing.T) {
+	for _, testCase := range parseZoneFileErrorTests {
+		t.Run(testCase.name, func(t *testing.T) { testParseZoneFileErrors(t, testCase) })
+	}
+}
+
+func testParseZoneFileErrors(t *testing.T, testCase parseZoneFileErrorTestCase) {
+	_, err := ParseZoneFile(strings.NewReader(testCase.contents), "example.zone", "example.com")
+	if assert.Error(t, err) {
+		assert.Equal(t, testCase.expectedError, err.Error())
+	}
+}
+
+type parseRecordFieldsTestCase struct {
+	recordType     dns.RecordType
+	value          string
+	expectedFields []string
+}
+
+var parseRecordFieldsTests = []parseRecordFieldsTestCase{
+	{dns.A, "192.0.2.1", []string{"192.0.2.1"}},
+	{dns.MX, "10 mail.example.com.", []string{"10", "mail.example.com"}},
+	{dns.CAA, `0 issue "letsencrypt.org"`, []string{"0", "issue", "letsencrypt.org"}},
+	{dns.TXT, `"first" "second \"quoted\""`, []string{"first", `second "quoted"`}},
+	{dns.TXT, `"ends with a dot."`, []string{"ends with a dot."}},
+	{dns.SRV, "10 60 5060 sip.example.com.", []string{"10", "60", "5060", "sip.example.com"}},
+	{dns.A, "", []string{}},
+}
+
+func TestParseRecordFields(t *testing.T) {
+	for _, testCase := range parseRecordFieldsTests {
+		t.Run(testCase.value, func(t *testing.T) { testParseRecordFields(t, testCase) })
+	}
+}
+
+func testParseRecordFields(t *testing.T, testCase parseRecordFieldsTestCase) {
+	fields, err := ParseRecordFields(testCase.recordType, testCase.value)
+	assert.NoError(t, err)
+	assert.Equal(t, testCase.expectedFields, fields)
+}
+
+func TestParseRecordFieldsErrors(t *testing.T) {
+	_, err := ParseRecordFields(dns.TXT, `"unterminated`)
+	assert.Error(t, err)
+
+	_, err = ParseRecordFields(dns.A, "192.0.2.1\n192.0.2.2")
+	assert.Error(t, err)
+}
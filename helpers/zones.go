@@ -0,0 +1,20 @@
+package helpers
+
+import (
+	"strings"
+)
+
+// ResourceGroupFromID extracts the name of the resource group from an Azure
+// resource ID, such as
+// /subscriptions/SUB/resourceGroups/RG/providers/Microsoft.Network/dnszones/ZONE.
+// If the ID does not contain a resource group, the empty string is returned.
+func ResourceGroupFromID(id string) string {
+	segments := strings.Split(strings.Trim(id, "/"), "/")
+	for i := 0; i+1 < len(segments); i += 2 {
+		if strings.EqualFold(segments[i], "resourceGroups") {
+			return segments[i+1]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,31 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type resourceGroupTestCase struct {
+	id                    string
+	expectedResourceGroup string
+}
+
+var resourceGroupTests = []resourceGroupTestCase{
+	{"/subscriptions/sub/resourceGroups/dns/providers/Microsoft.Network/dnszones/example.com", "dns"},
+	{"/subscriptions/sub/resourcegroups/DNS/providers/Microsoft.Network/dnszones/example.com", "DNS"},
+	{"/subscriptions/sub/resourceGroups/dns", "dns"},
+	{"/subscriptions/sub/resourceGroups/", ""},
+	{"/subscriptions/sub", ""},
+	{"", ""},
+}
+
+func TestResourceGroupFromID(t *testing.T) {
+	for _, testCase := range resourceGroupTests {
+		t.Run(testCase.id, func(t *testing.T) { testResourceGroupFromID(t, testCase) })
+	}
+}
+
+func testResourceGroupFromID(t *testing.T, testCase resourceGroupTestCase) {
+	assert.Equal(t, testCase.expectedResourceGroup, ResourceGroupFromID(testCase.id))
+}